@@ -9,6 +9,9 @@
 package thinker
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"github.com/kshard/chatter"
 )
 
@@ -26,8 +29,29 @@ const (
 	AGENT_REFINE
 	// Agent aborts processing due to unrecoverable error
 	AGENT_ABORT
+	// Agent escalates the question to human, pausing until the answer
+	AGENT_ESCALATE
 )
 
+func (phase Phase) String() string {
+	switch phase {
+	case AGENT_ASK:
+		return "ask"
+	case AGENT_RETURN:
+		return "return"
+	case AGENT_RETRY:
+		return "retry"
+	case AGENT_REFINE:
+		return "refine"
+	case AGENT_ABORT:
+		return "abort"
+	case AGENT_ESCALATE:
+		return "escalate"
+	default:
+		return fmt.Sprintf("phase(%d)", int(phase))
+	}
+}
+
 // State of the agent, maintained by the agent and used by Reasoner.
 type State[B any] struct {
 	// Execution phase of the agent
@@ -36,6 +60,9 @@ type State[B any] struct {
 	// Current epoch of execution phase
 	Epoch int
 
+	// The prompt that has started the current step of execution
+	Goal chatter.Message
+
 	// Reply from LLM
 	Reply B
 
@@ -44,4 +71,22 @@ type State[B any] struct {
 
 	// Feedback to LLM
 	Feedback chatter.Content
+
+	// History of tools invoked within the current step of execution
+	Tools []ToolCall
+}
+
+// Invocation of the tool, the part of the agent's state.
+type ToolCall struct {
+	// Epoch of the invocation
+	Epoch int
+
+	// Name of the tool
+	Tool string
+
+	// Arguments of the invocation
+	Args json.RawMessage
+
+	// Results of the tool, empty if the tool has not answered
+	Result json.RawMessage
 }
@@ -0,0 +1,181 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// ApproverFrom is helper to build thinker.Approver interface from callback.
+func ApproverFrom(f func(context.Context, chatter.Message) (chatter.Message, error)) thinker.Approver {
+	return fromApprover(f)
+}
+
+type fromApprover func(context.Context, chatter.Message) (chatter.Message, error)
+
+func (f fromApprover) Approve(ctx context.Context, question chatter.Message) (chatter.Message, error) {
+	return f(ctx, question)
+}
+
+//------------------------------------------------------------------------------
+
+// Escalation is the question pending the human answer.
+type Escalation struct {
+	Question chatter.Message
+	answer   chan<- approval
+}
+
+type approval struct {
+	msg chatter.Message
+	err error
+}
+
+// Answers the question, the agent resumes with the answer.
+func (e Escalation) Answer(msg chatter.Message) { e.answer <- approval{msg: msg} }
+
+// Rejects the question, the agent aborts with the error.
+func (e Escalation) Reject(err error) { e.answer <- approval{err: err} }
+
+// ChannelApprover delivers escalations to the channel, e.g. for the human
+// operating from another goroutine (web socket, chat bot, etc).
+type ChannelApprover struct {
+	ch chan Escalation
+}
+
+var _ thinker.Approver = (*ChannelApprover)(nil)
+
+// Creates new approver that delivers escalations to the channel.
+func NewChannelApprover() *ChannelApprover {
+	return &ChannelApprover{ch: make(chan Escalation)}
+}
+
+// Escalations pending the human answer.
+func (c *ChannelApprover) Escalations() <-chan Escalation { return c.ch }
+
+// Approve blocks until the question is answered or the context is cancelled.
+func (c *ChannelApprover) Approve(ctx context.Context, question chatter.Message) (chatter.Message, error) {
+	answer := make(chan approval, 1)
+
+	select {
+	case c.ch <- Escalation{Question: question, answer: answer}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case a := <-answer:
+		return a.msg, a.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ConsoleApprover asks the question at the terminal, the line is the answer.
+// The line is read in background, the approval is cancelled with the context.
+// The line read after cancellation answers the next question.
+type ConsoleApprover struct {
+	mu      sync.Mutex
+	r       *bufio.Reader
+	w       io.Writer
+	pending chan consoleLine
+}
+
+type consoleLine struct {
+	text string
+	err  error
+}
+
+var _ thinker.Approver = (*ConsoleApprover)(nil)
+
+// Creates new approver that asks questions at the terminal (e.g. os.Stdin, os.Stdout).
+func NewConsoleApprover(r io.Reader, w io.Writer) *ConsoleApprover {
+	return &ConsoleApprover{r: bufio.NewReader(r), w: w}
+}
+
+// Approve prints the question and reads the answer, the end of input rejects it.
+// It blocks until the answer is read or the context is cancelled.
+func (c *ConsoleApprover) Approve(ctx context.Context, question chatter.Message) (chatter.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := fmt.Fprintf(c.w, "%s\n> ", question.String()); err != nil {
+		return nil, err
+	}
+
+	if c.pending == nil {
+		c.pending = make(chan consoleLine, 1)
+		go func(ch chan<- consoleLine) {
+			text, err := c.r.ReadString('\n')
+			ch <- consoleLine{text: text, err: err}
+		}(c.pending)
+	}
+
+	select {
+	case line := <-c.pending:
+		c.pending = nil
+		if line.err != nil && (line.err != io.EOF || len(line.text) == 0) {
+			return nil, line.err
+		}
+		return chatter.Text(strings.TrimSpace(line.text)), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// escalates the question to human
+func escalate(ctx context.Context, approver thinker.Approver, question chatter.Message) (chatter.Message, error) {
+	if approver == nil {
+		return nil, thinker.ErrNoApprover.With(thinker.ErrAborted)
+	}
+
+	answer, err := approver.Approve(ctx, question)
+	if err != nil {
+		return nil, thinker.ErrAborted.With(err)
+	}
+
+	return answer, nil
+}
+
+// answers the tool call with the human answer, the invocation must be
+// followed by results of the tool (see memory.NewStream).
+func bind(question, answer chatter.Message) (chatter.Message, error) {
+	call, ok := question.(*chatter.Answer)
+	if !ok {
+		return answer, nil
+	}
+
+	if _, ok := answer.(*chatter.Answer); ok {
+		return answer, nil
+	}
+
+	value, err := json.Marshal(map[string]any{"toolOutput": answer.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	yield := make([]chatter.Json, len(call.Yield))
+	for i, y := range call.Yield {
+		yield[i] = chatter.Json{ID: y.ID, Source: y.Source, Value: value}
+	}
+
+	return &chatter.Answer{Yield: yield}, nil
+}
@@ -0,0 +1,181 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/agent"
+	"github.com/kshard/thinker/codec"
+	"github.com/kshard/thinker/memory"
+	"github.com/kshard/thinker/reasoner"
+)
+
+// EscalateRegistry escalates every tool call to human.
+type EscalateRegistry struct{}
+
+func (r *EscalateRegistry) Context() chatter.Registry { return chatter.Registry{} }
+
+func (r *EscalateRegistry) Invoke(reply *chatter.Reply) (thinker.Phase, chatter.Message, error) {
+	return thinker.AGENT_ESCALATE, &chatter.Answer{
+		Yield: []chatter.Json{{ID: "call-1", Source: "deploy"}},
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+// Test Approver
+//------------------------------------------------------------------------------
+
+func TestApprover(t *testing.T) {
+	// signs off the reply before returning it
+	signoff := reasoner.From(func(state thinker.State[string]) (thinker.Phase, chatter.Message, error) {
+		if state.Phase == thinker.AGENT_ESCALATE {
+			return thinker.AGENT_RETURN, nil, nil
+		}
+		return thinker.AGENT_ESCALATE, chatter.Text("Approve the reply?"), nil
+	})
+
+	automata := func() *agent.Automata[string, string] {
+		return agent.NewAutomata(&Mock{}, memory.NewVoid(""), codec.String, codec.String, signoff)
+	}
+
+	t.Run("Automata", func(t *testing.T) {
+		var question string
+		approver := agent.ApproverFrom(func(ctx context.Context, q chatter.Message) (chatter.Message, error) {
+			question = q.String()
+			return chatter.Text("approved"), nil
+		})
+
+		reply, err := automata().WithApprover(approver).Prompt(context.Background(), "input")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(question, "Approve the reply?"),
+			it.String(reply).Contain("approved"),
+		)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		errRejected := errors.New("rejected")
+		approver := agent.ApproverFrom(func(ctx context.Context, q chatter.Message) (chatter.Message, error) {
+			return nil, errRejected
+		})
+
+		_, err := automata().WithApprover(approver).Prompt(context.Background(), "input")
+		it.Then(t).Should(
+			it.True(errors.Is(err, thinker.ErrAborted)),
+			it.True(errors.Is(err, errRejected)),
+		)
+	})
+
+	t.Run("NoApprover", func(t *testing.T) {
+		_, err := automata().Prompt(context.Background(), "input")
+		it.Then(t).Should(
+			it.True(errors.Is(err, thinker.ErrNoApprover)),
+			it.True(errors.Is(err, thinker.ErrAborted)),
+		)
+	})
+
+	t.Run("Manifold", func(t *testing.T) {
+		mem := memory.NewStream(-1, "")
+		approver := agent.ApproverFrom(func(ctx context.Context, q chatter.Message) (chatter.Message, error) {
+			return chatter.Text("yes, deploy"), nil
+		})
+
+		_, err := agent.NewManifold(&InvokeThenReturnMock{}, codec.String, codec.String, &EscalateRegistry{}).
+			WithMemory(mem).
+			WithApprover(approver).
+			Prompt(context.Background(), "input")
+		it.Then(t).Must(it.Nil(err))
+
+		var answer *chatter.Answer
+		for obs := range mem.Observations() {
+			if a, ok := obs.Query.Content.(*chatter.Answer); ok {
+				answer = a
+			}
+		}
+
+		it.Then(t).Must(it.True(answer != nil))
+		it.Then(t).Should(
+			it.Equal(len(answer.Yield), 1),
+			it.Equal(answer.Yield[0].ID, "call-1"),
+			it.String(string(answer.Yield[0].Value)).Contain("yes, deploy"),
+		)
+	})
+
+	t.Run("Channel", func(t *testing.T) {
+		approver := agent.NewChannelApprover()
+		go func() {
+			for e := range approver.Escalations() {
+				e.Answer(chatter.Text("approved by " + e.Question.String()))
+			}
+		}()
+
+		reply, err := automata().WithApprover(approver).Prompt(context.Background(), "input")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.String(reply).Contain("approved by Approve the reply?"),
+		)
+	})
+
+	t.Run("ChannelCancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := automata().WithApprover(agent.NewChannelApprover()).Prompt(ctx, "input")
+		it.Then(t).Should(
+			it.True(errors.Is(err, context.Canceled)),
+		)
+	})
+
+	t.Run("Console", func(t *testing.T) {
+		var out strings.Builder
+		approver := agent.NewConsoleApprover(strings.NewReader("looks good\n"), &out)
+
+		reply, err := automata().WithApprover(approver).Prompt(context.Background(), "input")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.String(out.String()).Contain("Approve the reply?"),
+			it.String(reply).Contain("looks good"),
+		)
+
+		_, err = approver.Approve(context.Background(), chatter.Text("again?"))
+		it.Then(t).ShouldNot(
+			it.Nil(err),
+		)
+	})
+
+	t.Run("ConsoleCancel", func(t *testing.T) {
+		r, w := io.Pipe()
+		defer w.Close()
+		approver := agent.NewConsoleApprover(r, io.Discard)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := approver.Approve(ctx, chatter.Text("approve?"))
+		it.Then(t).Should(
+			it.True(errors.Is(err, context.DeadlineExceeded)),
+		)
+
+		go w.Write([]byte("late\n"))
+		answer, err := approver.Approve(context.Background(), chatter.Text("again?"))
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(answer.String(), "late"),
+		)
+	})
+}
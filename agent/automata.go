@@ -11,6 +11,7 @@ package agent
 import (
 	"context"
 	"errors"
+	"iter"
 
 	"github.com/kshard/chatter"
 	"github.com/kshard/thinker"
@@ -23,6 +24,9 @@ type Automata[A, B any] struct {
 	reasoner thinker.Reasoner[B]
 	encoder  thinker.Encoder[A]
 	decoder  thinker.Decoder[B]
+	retry    Retry
+	approver thinker.Approver
+	model    string
 }
 
 func NewAutomata[A, B any](
@@ -41,45 +45,141 @@ func NewAutomata[A, B any](
 	}
 }
 
-// Prompt agent
+// Configures the retry policy of transient LLM errors.
+func (automata *Automata[A, B]) WithRetry(retry Retry) *Automata[A, B] {
+	automata.retry = retry
+	return automata
+}
+
+// Configures the model name of the LLM, the usage of tokens is accounted
+// to this model (see ledger).
+func (automata *Automata[A, B]) WithModel(model string) *Automata[A, B] {
+	automata.model = model
+	return automata
+}
+
+// Configures the human in the loop, it answers questions escalated by the reasoner.
+func (automata *Automata[A, B]) WithApprover(approver thinker.Approver) *Automata[A, B] {
+	automata.approver = approver
+	return automata
+}
+
+// Prompt agent. Every prompt is the run, isolated from other runs: the
+// reasoner is forked (see thinker.ForkReasoner) and purged, the reasoner
+// and the memory are notified about the lifecycle of the run.
 func (automata *Automata[A, B]) Prompt(ctx context.Context, input A, opt ...chatter.Opt) (B, error) {
+	return automata.exec(ctx, input, silent[B])
+}
+
+// Stream is the streaming variant of Prompt, it yields events of the run.
+// The run is stopped if the iteration is stopped.
+func (automata *Automata[A, B]) Stream(ctx context.Context, input A, opt ...chatter.Opt) iter.Seq2[Event[B], error] {
+	return stream(func(emit emitter[B]) (B, error) {
+		return automata.exec(ctx, input, emit)
+	})
+}
+
+func (automata *Automata[A, B]) exec(ctx context.Context, input A, emit emitter[B]) (_ B, err error) {
+	ctx = scope(ctx, "automata", automata.model)
+	ctx, span := thinker.StartSpan(ctx, "agent.automata")
+	defer func() { span.End(err) }()
+
+	reasoner := thinker.ReasonerOf(automata.reasoner)
+	reasoner.Purge()
+	memory := thinker.MemoryOf(ctx, automata.memory)
+
+	thinker.OnStart(ctx, reasoner, memory)
+	reply, err := automata.run(ctx, reasoner, memory, input, emit)
+	if err != nil {
+		thinker.OnAbort(ctx, err, reasoner, memory)
+		return reply, err
+	}
+
+	thinker.OnEnd(ctx, reasoner, memory)
+	return reply, nil
+}
+
+// Prompt agent with the empty memory, forgetting all past observations.
+func (automata *Automata[A, B]) PromptOnce(ctx context.Context, input A, opt ...chatter.Opt) (B, error) {
+	thinker.MemoryOf(ctx, automata.memory).Reset()
+	return automata.Prompt(ctx, input, opt...)
+}
+
+func (automata *Automata[A, B]) run(ctx context.Context, reasoner thinker.Reasoner[B], memory thinker.Memory, input A, emit emitter[B]) (_ B, err error) {
 	var nul B
-	state := thinker.State[B]{Phase: thinker.AGENT_ASK, Epoch: 0}
+
+	// every epoch is traced as the span, the last one ends with the run
+	var span thinker.Span
+	defer func() {
+		if span != nil {
+			span.End(err)
+		}
+	}()
 
 	prompt, err := automata.encoder.Encode(input)
 	if err != nil {
 		return nul, err
 	}
-	shortMemory := automata.memory.Context(prompt)
+	state := thinker.State[B]{Phase: thinker.AGENT_ASK, Epoch: 0, Goal: prompt}
+	shortMemory := memory.Context(prompt)
+
+	for epoch := 1; ; epoch++ {
+		if span != nil {
+			span.End(nil)
+		}
+		var ectx context.Context
+		ectx, span = thinker.StartSpan(ctx, "agent.epoch")
+		span.With("epoch", epoch)
+
+		if err := emit(Event[B]{Kind: EVENT_EPOCH, Epoch: epoch, Message: prompt}); err != nil {
+			return nul, err
+		}
 
-	for {
-		reply, err := automata.llm.Prompt(ctx, shortMemory)
+		token := func(kind EventKind, text string) error {
+			return emit(Event[B]{Kind: kind, Epoch: epoch, Token: text})
+		}
+		reply, err := automata.retry.stream(ectx, automata.llm, shortMemory, token)
+		if errors.Is(err, errStopped) {
+			return nul, err
+		}
 		if err != nil {
 			return nul, thinker.ErrLLM.With(err)
 		}
+		if err := emit(Event[B]{Kind: EVENT_REPLY, Epoch: epoch, Reply: reply}); err != nil {
+			return nul, err
+		}
 
+		state.Feedback = nil
 		state.Confidence, state.Reply, err = automata.decoder.Decode(reply)
 		if err != nil {
 			if ok := errors.As(err, &state.Feedback); !ok {
 				return nul, err
 			}
 		}
+		span.With("confidence", state.Confidence)
+		if err := emit(Event[B]{Kind: EVENT_DECODE, Epoch: epoch, Value: state.Reply, Confidence: state.Confidence, Feedback: state.Feedback}); err != nil {
+			return nul, err
+		}
 
 		state.Epoch++
 		if state.Phase != thinker.AGENT_RETRY {
-			automata.memory.Commit(thinker.NewObservation(prompt, reply))
+			memory.Commit(thinker.NewObservation(prompt, reply))
 		}
 
-		phase, request, err := automata.reasoner.Deduct(state)
+		phase, request, err := reasoner.Deduct(state)
 		if err != nil {
 			return nul, err
 		}
+		span.With("phase", phase.String())
+		if err := emit(Event[B]{Kind: EVENT_DECIDE, Epoch: epoch, Phase: phase, Message: request}); err != nil {
+			return nul, err
+		}
 
 		switch phase {
 		case thinker.AGENT_ASK:
-			state = thinker.State[B]{Phase: thinker.AGENT_ASK, Epoch: 0}
+			state = thinker.State[B]{Phase: thinker.AGENT_ASK, Epoch: 0, Goal: request}
 			prompt = request
-			shortMemory = automata.memory.Context(prompt)
+			shortMemory = memory.Context(prompt)
 			continue
 		case thinker.AGENT_RETURN:
 			return state.Reply, nil
@@ -89,7 +189,15 @@ func (automata *Automata[A, B]) Prompt(ctx context.Context, input A, opt ...chat
 		case thinker.AGENT_REFINE:
 			state.Phase = phase
 			prompt = request
-			shortMemory = automata.memory.Context(prompt)
+			shortMemory = memory.Context(prompt)
+		case thinker.AGENT_ESCALATE:
+			answer, err := escalate(ectx, automata.approver, request)
+			if err != nil {
+				return nul, err
+			}
+			state.Phase = phase
+			prompt = answer
+			shortMemory = memory.Context(prompt)
 		case thinker.AGENT_ABORT:
 			return nul, thinker.ErrAborted.With(err)
 		default:
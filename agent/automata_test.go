@@ -0,0 +1,77 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/agent"
+	"github.com/kshard/thinker/codec"
+	"github.com/kshard/thinker/memory"
+	"github.com/kshard/thinker/reasoner"
+)
+
+//------------------------------------------------------------------------------
+// Test Automata
+//------------------------------------------------------------------------------
+
+func TestAutomata(t *testing.T) {
+	// GoalOfStep verifies that the reasoner observes the prompt that has
+	// started the step, refine prompts do not change the goal.
+	t.Run("GoalOfStep", func(t *testing.T) {
+		goals := make([]string, 0)
+		automata := agent.NewAutomata(&Mock{},
+			memory.NewVoid(""),
+			codec.String,
+			codec.String,
+			reasoner.From(func(state thinker.State[string]) (thinker.Phase, chatter.Message, error) {
+				goals = append(goals, state.Goal.String())
+				if state.Epoch < 2 {
+					return thinker.AGENT_REFINE, chatter.Text("refine"), nil
+				}
+				return thinker.AGENT_RETURN, nil, nil
+			}),
+		)
+
+		_, err := automata.Prompt(context.Background(), "goal")
+		it.Then(t).Must(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Seq(goals).Equal("goal.", "goal."),
+		)
+	})
+
+	// Feedback verifies that the feedback of the rejected reply does not
+	// outlive the epoch, the accepted reply is returned.
+	t.Run("Feedback", func(t *testing.T) {
+		decoded := 0
+		automata := agent.NewAutomata(&Mock{},
+			memory.NewVoid(""),
+			codec.String,
+			codec.FromDecoder(func(reply *chatter.Reply) (float64, string, error) {
+				decoded++
+				if decoded == 1 {
+					return 0.0, "", thinker.Feedback("rejected")
+				}
+				return 1.0, reply.String(), nil
+			}),
+			reasoner.NewEpoch(5, reasoner.NewFSM[string]("s").WithState("s", "")),
+		)
+
+		_, err := automata.Prompt(context.Background(), "goal")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(decoded, 2),
+		)
+	})
+}
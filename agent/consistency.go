@@ -0,0 +1,227 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/memory"
+)
+
+// Ballot is the outcome of voting.
+type Ballot[B any] struct {
+	// The majority answer
+	Value B
+
+	// The reply of LLM that has given the answer
+	Reply *chatter.Reply
+
+	// Number of samples agreed on the answer
+	Votes int
+
+	// Number of samples, including failed ones
+	Samples int
+
+	// Agreement ratio, votes over samples
+	Confidence float64
+}
+
+// Self-consistency agent samples the same prompt several times and returns
+// the majority answer. Answers are compared by the canonical form, ties are
+// broken by the total confidence of the decoder.
+type SelfConsistency[A, B any] struct {
+	llm         chatter.Chatter
+	memory      thinker.Memory
+	encoder     thinker.Encoder[A]
+	decoder     thinker.Decoder[B]
+	canonical   func(B) string
+	samples     int
+	concurrency int
+	retry       Retry
+	model       string
+}
+
+// Creates new self-consistency agent that votes over the samples. By default,
+// samples are requested in parallel and compared by their JSON form.
+func NewSelfConsistency[A, B any](
+	llm chatter.Chatter,
+	encoder thinker.Encoder[A],
+	decoder thinker.Decoder[B],
+	samples int,
+) *SelfConsistency[A, B] {
+	return &SelfConsistency[A, B]{
+		llm:         llm,
+		memory:      memory.NewVoid(""),
+		encoder:     encoder,
+		decoder:     decoder,
+		canonical:   canonical[B],
+		samples:     max(samples, 1),
+		concurrency: max(samples, 1),
+	}
+}
+
+func (sc *SelfConsistency[A, B]) WithMemory(memory thinker.Memory) *SelfConsistency[A, B] {
+	sc.memory = memory
+	return sc
+}
+
+// Configures the canonical form of answers, equal answers have the same form.
+//
+//	WithCanonical(func(s string) string { return strings.ToLower(strings.TrimSpace(s)) })
+func (sc *SelfConsistency[A, B]) WithCanonical(f func(B) string) *SelfConsistency[A, B] {
+	sc.canonical = f
+	return sc
+}
+
+// Configures the maximum number of concurrent LLM calls.
+func (sc *SelfConsistency[A, B]) WithConcurrency(n int) *SelfConsistency[A, B] {
+	sc.concurrency = max(n, 1)
+	return sc
+}
+
+// Configures the retry policy of transient LLM errors.
+func (sc *SelfConsistency[A, B]) WithRetry(retry Retry) *SelfConsistency[A, B] {
+	sc.retry = retry
+	return sc
+}
+
+// Configures the model name of the LLM, the usage of tokens is accounted
+// to this model (see ledger).
+func (sc *SelfConsistency[A, B]) WithModel(model string) *SelfConsistency[A, B] {
+	sc.model = model
+	return sc
+}
+
+// Prompt agent, it returns the majority answer.
+func (sc *SelfConsistency[A, B]) Prompt(ctx context.Context, input A, opt ...chatter.Opt) (B, error) {
+	ballot, err := sc.Vote(ctx, input, opt...)
+	if err != nil {
+		var nul B
+		return nul, err
+	}
+
+	return ballot.Value, nil
+}
+
+// Vote samples the prompt and returns the ballot of the majority answer.
+// Samples failed or rejected by the decoder do not vote. The error is
+// returned only if none of samples votes.
+func (sc *SelfConsistency[A, B]) Vote(ctx context.Context, input A, opt ...chatter.Opt) (Ballot[B], error) {
+	ctx = scope(ctx, "self-consistency", sc.model)
+	memory := thinker.MemoryOf(ctx, sc.memory)
+
+	thinker.OnStart(ctx, memory)
+	ballot, err := sc.vote(ctx, memory, input, opt...)
+	if err != nil {
+		thinker.OnAbort(ctx, err, memory)
+		return ballot, err
+	}
+
+	thinker.OnEnd(ctx, memory)
+	return ballot, nil
+}
+
+func (sc *SelfConsistency[A, B]) vote(ctx context.Context, memory thinker.Memory, input A, opt ...chatter.Opt) (Ballot[B], error) {
+	prompt, err := sc.encoder.Encode(input)
+	if err != nil {
+		return Ballot[B]{}, thinker.ErrCodec.With(err)
+	}
+
+	shortMemory := memory.Context(prompt)
+
+	replies, errs := sc.sample(ctx, shortMemory, opt...)
+
+	type candidate struct {
+		Ballot[B]
+		weight float64
+	}
+
+	var winner *candidate
+	candidates := make(map[string]*candidate)
+	for i, reply := range replies {
+		if reply == nil {
+			continue
+		}
+
+		confidence, value, err := sc.decoder.Decode(reply)
+		if err != nil {
+			var feedback chatter.Content
+			if ok := errors.As(err, &feedback); !ok {
+				errs[i] = err
+			}
+			continue
+		}
+
+		key := sc.canonical(value)
+		c, has := candidates[key]
+		if !has {
+			c = &candidate{Ballot: Ballot[B]{Value: value, Reply: reply}}
+			candidates[key] = c
+		}
+		c.Votes++
+		c.weight += confidence
+
+		if winner == nil || c.Votes > winner.Votes || (c.Votes == winner.Votes && c.weight > winner.weight) {
+			winner = c
+		}
+	}
+
+	if winner == nil {
+		if err := errors.Join(errs...); err != nil {
+			return Ballot[B]{}, err
+		}
+		return Ballot[B]{}, thinker.ErrCodec.With(fmt.Errorf("none of %d samples is decoded", sc.samples))
+	}
+
+	memory.Commit(thinker.NewObservation(prompt, winner.Reply))
+
+	ballot := winner.Ballot
+	ballot.Samples = sc.samples
+	ballot.Confidence = float64(ballot.Votes) / float64(sc.samples)
+	return ballot, nil
+}
+
+// samples the prompt in parallel, with bounded concurrency
+func (sc *SelfConsistency[A, B]) sample(ctx context.Context, seq []chatter.Message, opt ...chatter.Opt) ([]*chatter.Reply, []error) {
+	replies := make([]*chatter.Reply, sc.samples)
+	errs := make([]error, sc.samples)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, sc.concurrency)
+	for i := range sc.samples {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+
+			reply, err := sc.retry.prompt(ctx, sc.llm, seq, opt...)
+			if err != nil {
+				errs[i] = thinker.ErrLLM.With(err)
+				return
+			}
+			replies[i] = reply
+		})
+	}
+	wg.Wait()
+
+	return replies, errs
+}
+
+// canonical form of the answer
+func canonical[B any](value B) string {
+	if b, err := json.Marshal(value); err == nil {
+		return string(b)
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,167 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/agent"
+	"github.com/kshard/thinker/codec"
+	"github.com/kshard/thinker/memory"
+)
+
+// SampleMock replies with the answers in order, tracking concurrent calls.
+type SampleMock struct {
+	mu       sync.Mutex
+	answers  []string
+	calls    int
+	inflight int
+	peak     int
+}
+
+func (m *SampleMock) Usage() chatter.Usage { return chatter.Usage{} }
+
+func (m *SampleMock) Prompt(_ context.Context, _ []chatter.Message, _ ...chatter.Opt) (*chatter.Reply, error) {
+	m.mu.Lock()
+	answer := m.answers[m.calls%len(m.answers)]
+	m.calls++
+	m.inflight++
+	m.peak = max(m.peak, m.inflight)
+	m.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	m.mu.Lock()
+	m.inflight--
+	m.mu.Unlock()
+
+	if answer == "!" {
+		return nil, errors.New("llm error")
+	}
+
+	return &chatter.Reply{
+		Stage:   chatter.LLM_RETURN,
+		Content: []chatter.Content{chatter.Text(answer)},
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+// Test Self Consistency
+//------------------------------------------------------------------------------
+
+func TestSelfConsistency(t *testing.T) {
+	t.Run("Majority", func(t *testing.T) {
+		llm := &SampleMock{answers: []string{"a", "b", "a", "c", "a"}}
+		ballot, err := agent.NewSelfConsistency(llm, codec.String, codec.String, 5).
+			Vote(context.Background(), "classify")
+
+		it.Then(t).Must(it.Nil(err))
+		it.Then(t).Should(
+			it.Equal(ballot.Value, "a"),
+			it.Equal(ballot.Votes, 3),
+			it.Equal(ballot.Samples, 5),
+			it.Equal(ballot.Confidence, 0.6),
+			it.Equal(llm.calls, 5),
+		)
+	})
+
+	t.Run("TieBreak", func(t *testing.T) {
+		decoder := codec.FromDecoder(func(reply *chatter.Reply) (float64, string, error) {
+			if reply.String() == "b" {
+				return 0.9, "b", nil
+			}
+			return 0.4, reply.String(), nil
+		})
+
+		reply, err := agent.NewSelfConsistency(&SampleMock{answers: []string{"a", "b"}}, codec.String, decoder, 4).
+			Prompt(context.Background(), "classify")
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(reply, "b"),
+		)
+	})
+
+	t.Run("Canonical", func(t *testing.T) {
+		llm := &SampleMock{answers: []string{"Yes", "no", " yes", "YES "}}
+		ballot, err := agent.NewSelfConsistency(llm, codec.String, codec.String, 4).
+			WithCanonical(func(s string) string { return strings.ToLower(strings.TrimSpace(s)) }).
+			Vote(context.Background(), "classify")
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(strings.ToLower(strings.TrimSpace(ballot.Value)), "yes"),
+			it.Equal(ballot.Votes, 3),
+		)
+	})
+
+	t.Run("Concurrency", func(t *testing.T) {
+		llm := &SampleMock{answers: []string{"a"}}
+		_, err := agent.NewSelfConsistency(llm, codec.String, codec.String, 6).
+			WithConcurrency(2).
+			Prompt(context.Background(), "classify")
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(llm.calls, 6),
+			it.Equal(llm.peak, 2),
+		)
+	})
+
+	t.Run("FailedSamples", func(t *testing.T) {
+		decoder := codec.FromDecoder(func(reply *chatter.Reply) (float64, string, error) {
+			if reply.String() == "?" {
+				return 0, "", feedbackErr("Unknown class.")
+			}
+			if reply.String() == "b" {
+				return 0.5, "b", nil
+			}
+			return 1.0, reply.String(), nil
+		})
+
+		ballot, err := agent.NewSelfConsistency(&SampleMock{answers: []string{"a", "!", "?", "b"}}, codec.String, decoder, 4).
+			Vote(context.Background(), "classify")
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(ballot.Value, "a"),
+			it.Equal(ballot.Confidence, 0.25),
+		)
+	})
+
+	t.Run("NoVotes", func(t *testing.T) {
+		_, err := agent.NewSelfConsistency(&SampleMock{answers: []string{"!"}}, codec.String, codec.String, 3).
+			Prompt(context.Background(), "classify")
+
+		it.Then(t).Should(
+			it.True(errors.Is(err, thinker.ErrLLM)),
+		)
+	})
+
+	t.Run("Memory", func(t *testing.T) {
+		mem := memory.NewStream(-1, "")
+		_, err := agent.NewSelfConsistency(&SampleMock{answers: []string{"a", "b", "a"}}, codec.String, codec.String, 3).
+			WithMemory(mem).
+			Prompt(context.Background(), "classify")
+
+		it.Then(t).Must(it.Nil(err))
+		it.Then(t).Should(
+			it.Equal(mem.Len(), 1),
+			it.Equal(mem.Context(nil)[1].String(), "a"),
+		)
+	})
+}
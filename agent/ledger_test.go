@@ -0,0 +1,84 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/thinker/agent"
+	"github.com/kshard/thinker/codec"
+	"github.com/kshard/thinker/ledger"
+	"github.com/kshard/thinker/memory"
+	"github.com/kshard/thinker/reasoner"
+)
+
+func TestLedger(t *testing.T) {
+	t.Run("Automata", func(t *testing.T) {
+		l := ledger.New().WithPrice("echo", ledger.Price{Input: 1e6, Output: 1e6})
+		ctx := ledger.WithModel(l.Context(context.Background()), "echo")
+
+		automata := agent.NewAutomata(&Mock{}, memory.NewVoid(""), codec.String, codec.String, reasoner.NewVoid[string]())
+		reply, err := automata.Prompt(ctx, "input")
+		it.Then(t).Must(it.Nil(err))
+
+		report := l.Report()
+		it.Then(t).Must(it.Equal(len(report.Lines), 1))
+		it.Then(t).Should(
+			it.Equal(report.Lines[0].Scope, ledger.Scope{Model: "echo", Agent: "automata"}),
+			it.Equal(report.Total.Tokens(), 2*len(reply)),
+			it.Equal(report.Total.Cost, float64(2*len(reply))),
+		)
+	})
+
+	t.Run("Manifold", func(t *testing.T) {
+		l := ledger.New()
+		ctx := ledger.WithAgent(l.Context(context.Background()), "react.prompt")
+
+		manifold := agent.NewManifold(&Mock{}, codec.String, codec.String, &MockRegistry{})
+		_, err := manifold.Prompt(ctx, "input")
+		it.Then(t).Must(it.Nil(err))
+
+		report := l.Report()
+		it.Then(t).Must(it.Equal(len(report.Lines), 1))
+		it.Then(t).Should(
+			it.Equal(report.Lines[0].Agent, "react.prompt"),
+		)
+	})
+
+	t.Run("WithModel", func(t *testing.T) {
+		l := ledger.New().WithPrice("echo", ledger.Price{Input: 1e6, Output: 1e6})
+
+		automata := agent.NewAutomata(&Mock{}, memory.NewVoid(""), codec.String, codec.String, reasoner.NewVoid[string]()).
+			WithModel("echo")
+		reply, err := automata.Prompt(l.Context(context.Background()), "input")
+		it.Then(t).Must(it.Nil(err))
+
+		manifold := agent.NewManifold(&Mock{}, codec.String, codec.String, &MockRegistry{}).
+			WithModel("echo")
+		_, err = manifold.Prompt(l.Context(context.Background()), "input")
+		it.Then(t).Must(it.Nil(err))
+
+		report := l.Report()
+		it.Then(t).Must(it.Equal(len(report.Lines), 2))
+		it.Then(t).Should(
+			it.Equal(report.Lines[0].Scope, ledger.Scope{Model: "echo", Agent: "automata"}),
+			it.Equal(report.Lines[0].Cost, float64(2*len(reply))),
+			it.Equal(report.Lines[1].Scope, ledger.Scope{Model: "echo", Agent: "manifold"}),
+			it.True(report.Lines[1].Cost > 0),
+		)
+	})
+
+	t.Run("Unbound", func(t *testing.T) {
+		automata := agent.NewAutomata(&Mock{}, memory.NewVoid(""), codec.String, codec.String, reasoner.NewVoid[string]())
+		_, err := automata.Prompt(context.Background(), "input")
+		it.Then(t).Should(it.Nil(err))
+	})
+}
@@ -0,0 +1,176 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/agent"
+	"github.com/kshard/thinker/codec"
+	"github.com/kshard/thinker/memory"
+	"github.com/kshard/thinker/reasoner"
+)
+
+// journal of lifecycle events
+type journal struct{ seq []string }
+
+func (j *journal) log(event string) { j.seq = append(j.seq, event) }
+
+func (j *journal) OnStart(context.Context)        { j.log("start") }
+func (j *journal) OnEnd(context.Context)          { j.log("end") }
+func (j *journal) OnAbort(context.Context, error) { j.log("abort") }
+
+// probe is the stateful reasoner, it returns at the second epoch of the run
+// or aborts if the reply is "abort".
+type probe struct {
+	*journal
+	deducts int
+}
+
+func (p *probe) Purge() { p.log("purge") }
+
+func (p *probe) Fork() thinker.Reasoner[string] {
+	p.log("fork")
+	return &probe{journal: p.journal}
+}
+
+func (p *probe) Deduct(state thinker.State[string]) (thinker.Phase, chatter.Message, error) {
+	p.deducts++
+	if strings.Contains(state.Reply, "abort") {
+		return thinker.AGENT_ABORT, nil, errors.New("abort")
+	}
+	if p.deducts < 2 {
+		return thinker.AGENT_RETRY, nil, nil
+	}
+	return thinker.AGENT_RETURN, nil, nil
+}
+
+// memory notified about lifecycle
+type probeMemory struct {
+	thinker.Memory
+	*journal
+}
+
+// registry notified about lifecycle
+type probeRegistry struct {
+	MockRegistry
+	*journal
+}
+
+//------------------------------------------------------------------------------
+// Test Lifecycle
+//------------------------------------------------------------------------------
+
+func TestLifecycle(t *testing.T) {
+	t.Run("Automata", func(t *testing.T) {
+		j := &journal{}
+		automata := agent.NewAutomata(&Mock{}, memory.NewVoid(""), codec.String, codec.String, &probe{journal: j})
+
+		// the run returns at the second epoch only if the reasoner is fresh
+		for range 2 {
+			_, err := automata.Prompt(context.Background(), "input")
+			it.Then(t).Must(it.Nil(err))
+		}
+
+		it.Then(t).Should(
+			it.Seq(j.seq).Equal(
+				"fork", "purge", "start", "end",
+				"fork", "purge", "start", "end",
+			),
+		)
+	})
+
+	t.Run("AutomataMemory", func(t *testing.T) {
+		j := &journal{}
+		mem := probeMemory{Memory: memory.NewVoid(""), journal: j}
+		automata := agent.NewAutomata(&Mock{}, mem, codec.String, codec.String, reasoner.NewVoid[string]())
+
+		_, err := automata.Prompt(context.Background(), "input")
+		it.Then(t).Must(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Seq(j.seq).Equal("start", "end"),
+		)
+	})
+
+	t.Run("AutomataAbort", func(t *testing.T) {
+		j := &journal{}
+		automata := agent.NewAutomata(&Mock{}, memory.NewVoid(""), codec.String, codec.String, &probe{journal: j})
+
+		_, err := automata.Prompt(context.Background(), "abort")
+		it.Then(t).ShouldNot(it.Nil(err))
+		it.Then(t).Should(
+			it.Seq(j.seq).Equal("fork", "purge", "start", "abort"),
+		)
+	})
+
+	t.Run("PromptOnce", func(t *testing.T) {
+		mem := memory.NewStream(-1, "")
+		automata := agent.NewAutomata(&Mock{}, mem, codec.String, codec.String, reasoner.NewVoid[string]())
+
+		automata.Prompt(context.Background(), "first")
+		automata.Prompt(context.Background(), "second")
+		it.Then(t).Should(it.Equal(mem.Len(), 2))
+
+		automata.PromptOnce(context.Background(), "third")
+		it.Then(t).Should(it.Equal(mem.Len(), 1))
+	})
+
+	t.Run("Manifold", func(t *testing.T) {
+		j := &journal{}
+		mem := probeMemory{Memory: memory.NewStream(-1, ""), journal: j}
+		registry := &probeRegistry{journal: j}
+		manifold := agent.NewManifold(&Mock{}, codec.String, codec.String, registry).WithMemory(mem)
+
+		_, err := manifold.Prompt(context.Background(), "input")
+		it.Then(t).Must(it.Nil(err))
+
+		_, err = agent.NewManifold(&ErrorMock{}, codec.String, codec.String, registry).
+			WithMemory(mem).
+			Prompt(context.Background(), "input")
+		it.Then(t).ShouldNot(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Seq(j.seq).Equal(
+				"start", "start", "end", "end",
+				"start", "start", "abort", "abort",
+			),
+		)
+	})
+
+	// Isolation verifies that the stateful reasoner is safe to reuse by
+	// concurrent runs of the agent.
+	t.Run("Isolation", func(t *testing.T) {
+		fsm := reasoner.NewFSM[string]("draft").
+			WithState("draft", "", reasoner.Transition[string]{Target: "review"}).
+			WithState("review", "Review the draft")
+
+		var wg sync.WaitGroup
+		errs := make([]error, 8)
+		for i := range errs {
+			wg.Go(func() {
+				automata := agent.NewAutomata(&Mock{}, memory.NewVoid(""), codec.String, codec.String, reasoner.NewEpoch(4, fsm))
+				_, errs[i] = automata.Prompt(context.Background(), "input")
+			})
+		}
+		wg.Wait()
+
+		it.Then(t).Should(
+			it.Nil(errors.Join(errs...)),
+			it.Equal(fsm.State(), "draft"),
+		)
+	})
+}
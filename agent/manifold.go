@@ -10,7 +10,11 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"iter"
+	"strings"
 
 	"github.com/kshard/chatter"
 	"github.com/kshard/thinker"
@@ -23,6 +27,12 @@ type Manifold[A, B any] struct {
 	encoder  thinker.Encoder[A]
 	decoder  thinker.Decoder[B]
 	registry thinker.Registry
+	retry    Retry
+	approver thinker.Approver
+	reasoner thinker.Reasoner[B]
+	steps    int
+	repeats  int
+	model    string
 }
 
 func NewManifold[A, B any](
@@ -37,6 +47,7 @@ func NewManifold[A, B any](
 		encoder:  encoder,
 		decoder:  decoder,
 		registry: registry,
+		reasoner: feedback[B]{},
 	}
 }
 
@@ -45,92 +56,395 @@ func (manifold *Manifold[A, B]) WithMemory(memory thinker.Memory) *Manifold[A, B
 	return manifold
 }
 
+// Configures the retry policy of transient LLM errors.
+func (manifold *Manifold[A, B]) WithRetry(retry Retry) *Manifold[A, B] {
+	manifold.retry = retry
+	return manifold
+}
+
+// Configures the model name of the LLM, the usage of tokens is accounted
+// to this model (see ledger).
+func (manifold *Manifold[A, B]) WithModel(model string) *Manifold[A, B] {
+	manifold.model = model
+	return manifold
+}
+
+// Configures the human in the loop, it answers questions escalated by the registry.
+// The answer to escalated tool call is given as results of the tool.
+func (manifold *Manifold[A, B]) WithApprover(approver thinker.Approver) *Manifold[A, B] {
+	manifold.approver = approver
+	return manifold
+}
+
+// Configures the reasoner, it decides upon the final reply of LLM or tools
+// (e.g. caps epochs, accepts the reply above the confidence threshold or judges
+// it). The reasoner observes the history of tool invocations as the part of
+// the state. By default, the reply is returned unless the decoder gives
+// the feedback, which is sent back to LLM.
+func (manifold *Manifold[A, B]) WithReasoner(reasoner thinker.Reasoner[B]) *Manifold[A, B] {
+	manifold.reasoner = reasoner
+	return manifold
+}
+
+// Configures the maximum number of steps (LLM calls) per run, consistent with
+// reasoner.Epoch. The run is aborted with thinker.StepsError once the budget
+// is exhausted. The number of steps is unbounded if zero.
+func (manifold *Manifold[A, B]) WithMaxSteps(n int) *Manifold[A, B] {
+	manifold.steps = n
+	return manifold
+}
+
+// Configures the maximum number of identical tool calls (same tools, same
+// arguments) in a row. The next identical call is not invoked, the model
+// receives the corrective feedback as results of the tool. The run is aborted
+// with thinker.StepsError if the model repeats the call again. The detection
+// is disabled if zero.
+func (manifold *Manifold[A, B]) WithMaxRepeats(n int) *Manifold[A, B] {
+	manifold.repeats = n
+	return manifold
+}
+
+// Prompt agent. Every prompt is the run, the memory and the registry are
+// notified about the lifecycle of the run.
 func (manifold *Manifold[A, B]) Prompt(ctx context.Context, input A, opt ...chatter.Opt) (B, error) {
+	return manifold.exec(ctx, input, silent[B], opt...)
+}
+
+// Stream is the streaming variant of Prompt, it yields events of the run,
+// including tool invocations. The run is stopped if the iteration is stopped.
+func (manifold *Manifold[A, B]) Stream(ctx context.Context, input A, opt ...chatter.Opt) iter.Seq2[Event[B], error] {
+	return stream(func(emit emitter[B]) (B, error) {
+		return manifold.exec(ctx, input, emit, opt...)
+	})
+}
+
+func (manifold *Manifold[A, B]) exec(ctx context.Context, input A, emit emitter[B], opt ...chatter.Opt) (_ B, err error) {
+	ctx = scope(ctx, "manifold", manifold.model)
+	ctx, span := thinker.StartSpan(ctx, "agent.manifold")
+	defer func() { span.End(err) }()
+
+	reasoner := thinker.ReasonerOf(manifold.reasoner)
+	reasoner.Purge()
+	memory := thinker.MemoryOf(ctx, manifold.memory)
+
+	thinker.OnStart(ctx, reasoner, memory, manifold.registry)
+	reply, err := manifold.run(ctx, reasoner, memory, input, emit, opt...)
+	if err != nil {
+		thinker.OnAbort(ctx, err, reasoner, memory, manifold.registry)
+		return reply, err
+	}
+
+	thinker.OnEnd(ctx, reasoner, memory, manifold.registry)
+	return reply, nil
+}
+
+// Prompt agent with the empty memory, forgetting all past observations.
+func (manifold *Manifold[A, B]) PromptOnce(ctx context.Context, input A, opt ...chatter.Opt) (B, error) {
+	thinker.MemoryOf(ctx, manifold.memory).Reset()
+	return manifold.Prompt(ctx, input, opt...)
+}
+
+func (manifold *Manifold[A, B]) run(ctx context.Context, reasoner thinker.Reasoner[B], memory thinker.Memory, input A, emit emitter[B], opt ...chatter.Opt) (_ B, err error) {
 	var nul B
 
+	// every epoch is traced as the span, the last one ends with the run
+	var span thinker.Span
+	defer func() {
+		if span != nil {
+			span.End(err)
+		}
+	}()
+
 	prompt, err := manifold.encoder.Encode(input)
 	if err != nil {
 		return nul, thinker.ErrCodec.With(err)
 	}
+	state := thinker.State[B]{Phase: thinker.AGENT_ASK, Epoch: 0, Goal: prompt}
 
 	opt = append(opt, manifold.registry.Context())
 
-	for {
-		shortMemory := manifold.memory.Context(prompt)
-		reply, err := manifold.llm.Prompt(ctx, shortMemory, opt...)
+	// the last tool call and the number of its identical repeats in a row
+	var (
+		tool, call string
+		repeats    int
+	)
+
+	// the context of the prompt, it is sent again if the reasoner retries
+	var shortMemory []chatter.Message
+	retry := false
+
+	for epoch := 1; ; epoch++ {
+		if manifold.steps > 0 && epoch > manifold.steps {
+			return nul, &thinker.StepsError{Steps: epoch - 1, Tool: tool, Err: thinker.ErrMaxSteps}
+		}
+
+		if span != nil {
+			span.End(nil)
+		}
+		var ectx context.Context
+		ectx, span = thinker.StartSpan(ctx, "agent.epoch")
+		span.With("epoch", epoch)
+
+		if err := emit(Event[B]{Kind: EVENT_EPOCH, Epoch: epoch, Message: prompt}); err != nil {
+			return nul, err
+		}
+
+		token := func(kind EventKind, text string) error {
+			return emit(Event[B]{Kind: kind, Epoch: epoch, Token: text})
+		}
+		if !retry {
+			shortMemory = memory.Context(prompt)
+		}
+		retry = false
+
+		reply, err := manifold.retry.stream(ectx, manifold.llm, shortMemory, token, opt...)
+		if errors.Is(err, errStopped) {
+			return nul, err
+		}
 		if err != nil {
 			return nul, thinker.ErrLLM.With(err)
 		}
-		manifold.memory.Commit(thinker.NewObservation(prompt, reply))
+		if err := emit(Event[B]{Kind: EVENT_REPLY, Epoch: epoch, Reply: reply}); err != nil {
+			return nul, err
+		}
+		state.Epoch++
 
-		switch reply.Stage {
-		case chatter.LLM_RETURN:
-			_, ret, err := manifold.decoder.Decode(reply)
-			if err != nil {
-				var feedback chatter.Content
-				if ok := errors.As(err, &feedback); !ok {
-					return nul, err
-				}
+		// the final reply is committed unless the reasoner retries it
+		observation := thinker.NewObservation(prompt, reply)
 
-				var fprompt chatter.Prompt
-				fprompt.With(feedback)
-				prompt = &fprompt
+		// the final reply, either from LLM or from tools, is decided by the reasoner
+		var final *chatter.Reply
+		var fault = func(err error) error { return err }
 
-				continue
+		switch reply.Stage {
+		case chatter.LLM_RETURN, chatter.LLM_INCOMPLETE:
+			final = reply
+		case chatter.LLM_INVOKE:
+			// the tool call is committed unless tools give the final reply
+			if err := emit(Event[B]{Kind: EVENT_INVOKE, Epoch: epoch, Reply: reply}); err != nil {
+				return nul, err
+			}
+
+			var sig string
+			tool, sig = signature(reply)
+			if sig == call {
+				repeats++
+			} else {
+				call, repeats = sig, 1
 			}
-			return ret, nil
-		case chatter.LLM_INCOMPLETE:
-			_, ret, err := manifold.decoder.Decode(reply)
-			if err != nil {
-				var feedback chatter.Content
-				if ok := errors.As(err, &feedback); !ok {
-					return nul, err
-				}
 
-				var fprompt chatter.Prompt
-				fprompt.With(feedback)
-				prompt = &fprompt
+			if manifold.repeats > 0 && repeats > manifold.repeats {
+				memory.Commit(observation)
+				if repeats > manifold.repeats+1 {
+					return nul, &thinker.StepsError{Steps: epoch, Tool: tool, Err: thinker.ErrToolLoop}
+				}
 
+				if prompt, err = repeated(reply, repeats); err != nil {
+					return nul, thinker.ErrCmd.With(err)
+				}
+				if err := emit(Event[B]{Kind: EVENT_ANSWER, Epoch: epoch, Phase: thinker.AGENT_REFINE, Message: prompt}); err != nil {
+					return nul, err
+				}
 				continue
 			}
-			return ret, nil
-		case chatter.LLM_INVOKE:
-			stage, answer, err := manifold.registry.Invoke(reply)
+
+			stage, answer, err := manifold.invoke(ectx, reply)
 			if err != nil {
-				var feedback chatter.Content
-				if ok := errors.As(err, &feedback); !ok {
+				memory.Commit(observation)
+				if prompt, err = refine(err); err != nil {
 					return nul, thinker.ErrCmd.With(err)
 				}
-
-				var fprompt chatter.Prompt
-				fprompt.With(feedback)
-				prompt = &fprompt
-
 				continue
 			}
+			state.Tools = append(state.Tools, calls(epoch, reply, answer)...)
+			if stage != thinker.AGENT_RETURN {
+				memory.Commit(observation)
+			}
+			if err := emit(Event[B]{Kind: EVENT_ANSWER, Epoch: epoch, Phase: stage, Message: answer}); err != nil {
+				return nul, err
+			}
+
 			switch stage {
 			case thinker.AGENT_RETURN:
-				_, ret, err := manifold.decoder.Decode(&chatter.Reply{Content: []chatter.Content{answer}})
+				final = &chatter.Reply{Content: []chatter.Content{answer}}
+				fault = func(err error) error { return thinker.ErrCmd.With(err) }
+			case thinker.AGENT_ESCALATE:
+				human, err := escalate(ectx, manifold.approver, answer)
 				if err != nil {
-					var feedback chatter.Content
-					if ok := errors.As(err, &feedback); !ok {
-						return nul, thinker.ErrCmd.With(err)
-					}
-
-					var fprompt chatter.Prompt
-					fprompt.With(feedback)
-					prompt = &fprompt
-
-					continue
+					return nul, err
+				}
+				if prompt, err = bind(answer, human); err != nil {
+					return nul, thinker.ErrCmd.With(err)
 				}
-				return ret, nil
+				continue
 			case thinker.AGENT_ABORT:
 				return nul, thinker.ErrAborted
 			default:
 				prompt = answer
+				continue
 			}
 		default:
+			memory.Commit(observation)
 			return nul, thinker.ErrAborted
 		}
+
+		state.Feedback = nil
+		state.Confidence, state.Reply, err = manifold.decoder.Decode(final)
+		if err != nil {
+			if ok := errors.As(err, &state.Feedback); !ok {
+				return nul, fault(err)
+			}
+		}
+		span.With("confidence", state.Confidence)
+		if err := emit(Event[B]{Kind: EVENT_DECODE, Epoch: epoch, Value: state.Reply, Confidence: state.Confidence, Feedback: state.Feedback}); err != nil {
+			return nul, err
+		}
+
+		phase, request, err := reasoner.Deduct(state)
+		if phase != thinker.AGENT_RETRY {
+			memory.Commit(observation)
+		}
+		if err != nil {
+			return nul, err
+		}
+		span.With("phase", phase.String())
+		if err := emit(Event[B]{Kind: EVENT_DECIDE, Epoch: epoch, Phase: phase, Message: request}); err != nil {
+			return nul, err
+		}
+
+		switch phase {
+		case thinker.AGENT_ASK:
+			state = thinker.State[B]{Phase: thinker.AGENT_ASK, Epoch: 0, Goal: request}
+			prompt = request
+		case thinker.AGENT_RETURN:
+			return state.Reply, nil
+		case thinker.AGENT_RETRY:
+			state.Phase = phase
+			retry = true
+		case thinker.AGENT_REFINE:
+			state.Phase = phase
+			prompt = request
+		case thinker.AGENT_ESCALATE:
+			answer, err := escalate(ectx, manifold.approver, request)
+			if err != nil {
+				return nul, err
+			}
+			state.Phase = phase
+			prompt = answer
+		case thinker.AGENT_ABORT:
+			return nul, thinker.ErrAborted.With(err)
+		default:
+			return nul, thinker.ErrAborted
+		}
+	}
+}
+
+// invokes tools requested by LLM, the invocation is traced as the span
+func (manifold *Manifold[A, B]) invoke(ctx context.Context, reply *chatter.Reply) (thinker.Phase, chatter.Message, error) {
+	_, span := thinker.StartSpan(ctx, "tool.invoke")
+
+	tool, _ := signature(reply)
+	span.With("tool", tool)
+
+	phase, answer, err := manifold.registry.Invoke(reply)
+	span.With("phase", phase.String()).End(err)
+	return phase, answer, err
+}
+
+// names of tools requested by LLM and the signature of the call, identical
+// calls (same tools, same arguments) have the same signature
+func signature(reply *chatter.Reply) (string, string) {
+	tools := make([]string, 0)
+	calls := make([]string, 0)
+	for _, c := range reply.Content {
+		if inv, ok := c.(chatter.Invoke); ok {
+			tools = append(tools, inv.Cmd)
+
+			// arguments are compared in the canonical form, keys are sorted
+			args := string(inv.Args.Value)
+			var v any
+			if err := json.Unmarshal(inv.Args.Value, &v); err == nil {
+				if b, err := json.Marshal(v); err == nil {
+					args = string(b)
+				}
+			}
+			calls = append(calls, inv.Cmd+args)
+		}
 	}
+
+	return strings.Join(tools, ","), strings.Join(calls, ";")
+}
+
+// the corrective feedback to the repeated tool call, given as results of tools
+func repeated(reply *chatter.Reply, repeats int) (chatter.Message, error) {
+	feedback := fmt.Sprintf("The tool has been called %d times in a row with the same arguments, "+
+		"the call is not executed again. Do not repeat the call: use results you already have, "+
+		"change the arguments or reply with the final answer.", repeats)
+
+	value, err := json.Marshal(map[string]any{"toolOutput": feedback})
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &chatter.Answer{Yield: make([]chatter.Json, 0)}
+	for _, c := range reply.Content {
+		if inv, ok := c.(chatter.Invoke); ok {
+			answer.Yield = append(answer.Yield, chatter.Json{ID: inv.Args.ID, Source: inv.Cmd, Value: value})
+		}
+	}
+
+	return answer, nil
+}
+
+// the prompt with the feedback to LLM, the error is returned if it is not the feedback
+func refine(err error) (chatter.Message, error) {
+	var feedback chatter.Content
+	if ok := errors.As(err, &feedback); !ok {
+		return nil, err
+	}
+
+	var prompt chatter.Prompt
+	prompt.With(feedback)
+	return &prompt, nil
+}
+
+// history of tool invocations, results are matched to invocations by the id
+func calls(epoch int, reply *chatter.Reply, answer chatter.Message) []thinker.ToolCall {
+	results := map[string]json.RawMessage{}
+	if yield, ok := answer.(*chatter.Answer); ok {
+		for _, y := range yield.Yield {
+			results[y.ID] = y.Value
+		}
+	}
+
+	seq := make([]thinker.ToolCall, 0)
+	for _, c := range reply.Content {
+		if inv, ok := c.(chatter.Invoke); ok {
+			seq = append(seq, thinker.ToolCall{
+				Epoch:  epoch,
+				Tool:   inv.Cmd,
+				Args:   inv.Args.Value,
+				Result: results[inv.Args.ID],
+			})
+		}
+	}
+	return seq
+}
+
+//------------------------------------------------------------------------------
+
+// the default reasoner of manifold, it returns the reply unless the decoder
+// gives the feedback, which refines the reply
+type feedback[B any] struct{}
+
+func (feedback[B]) Purge() {}
+
+func (feedback[B]) Deduct(state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
+	if state.Feedback == nil {
+		return thinker.AGENT_RETURN, nil, nil
+	}
+
+	var prompt chatter.Prompt
+	prompt.With(state.Feedback)
+	return thinker.AGENT_REFINE, &prompt, nil
 }
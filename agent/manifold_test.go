@@ -20,6 +20,7 @@ import (
 	"github.com/kshard/thinker/agent"
 	"github.com/kshard/thinker/codec"
 	"github.com/kshard/thinker/memory"
+	"github.com/kshard/thinker/reasoner"
 )
 
 //------------------------------------------------------------------------------
@@ -273,6 +274,36 @@ func (m *InvokeThenReturnMock) Prompt(_ context.Context, prompt []chatter.Messag
 	}, nil
 }
 
+// RepeatMock always invokes the tool, the arguments are given by the sequence,
+// the last one is repeated.
+type RepeatMock struct {
+	args  []string
+	calls int
+}
+
+func (m *RepeatMock) Usage() chatter.Usage { return chatter.Usage{} }
+
+func (m *RepeatMock) Prompt(_ context.Context, prompt []chatter.Message, _ ...chatter.Opt) (*chatter.Reply, error) {
+	args := m.args[min(m.calls, len(m.args)-1)]
+	m.calls++
+	return &chatter.Reply{
+		Stage: chatter.LLM_INVOKE,
+		Content: []chatter.Content{
+			chatter.Invoke{Cmd: "search", Args: chatter.Json{ID: "id", Value: []byte(args)}},
+		},
+	}, nil
+}
+
+// CountRegistry counts invocations and loops back with the tool answer.
+type CountRegistry struct{ calls int }
+
+func (r *CountRegistry) Context() chatter.Registry { return chatter.Registry{} }
+
+func (r *CountRegistry) Invoke(reply *chatter.Reply) (thinker.Phase, chatter.Message, error) {
+	r.calls++
+	return thinker.AGENT_ASK, chatter.Text("tool result"), nil
+}
+
 // IncompleteMock echoes its input but with Stage LLM_INCOMPLETE.
 type IncompleteMock struct{}
 
@@ -316,6 +347,59 @@ func (r *AbortRegistry) Invoke(_ *chatter.Reply) (thinker.Phase, chatter.Message
 	return thinker.AGENT_ABORT, nil, nil
 }
 
+// ToolThenReturnMock invokes the tool on the first call, then echoes on subsequent calls.
+type ToolThenReturnMock struct {
+	calls int
+}
+
+func (m *ToolThenReturnMock) Usage() chatter.Usage { return chatter.Usage{} }
+
+func (m *ToolThenReturnMock) Prompt(_ context.Context, prompt []chatter.Message, _ ...chatter.Opt) (*chatter.Reply, error) {
+	m.calls++
+	if m.calls == 1 {
+		return &chatter.Reply{
+			Stage: chatter.LLM_INVOKE,
+			Content: []chatter.Content{
+				chatter.Invoke{Cmd: "search", Args: chatter.Json{ID: "id", Value: []byte(`{"q":1}`)}},
+			},
+		}, nil
+	}
+	return &chatter.Reply{
+		Stage:   chatter.LLM_RETURN,
+		Content: []chatter.Content{chatter.Text(prompt[len(prompt)-1].String())},
+	}, nil
+}
+
+// ContextMock echoes the last message, it records the length of every context.
+type ContextMock struct {
+	seq []int
+}
+
+func (m *ContextMock) Usage() chatter.Usage { return chatter.Usage{} }
+
+func (m *ContextMock) Prompt(_ context.Context, prompt []chatter.Message, _ ...chatter.Opt) (*chatter.Reply, error) {
+	m.seq = append(m.seq, len(prompt))
+	return &chatter.Reply{
+		Stage:   chatter.LLM_RETURN,
+		Content: []chatter.Content{chatter.Text(prompt[len(prompt)-1].String())},
+	}, nil
+}
+
+// AnswerRegistry answers every tool call, it loops back with the answer.
+type AnswerRegistry struct{}
+
+func (r *AnswerRegistry) Context() chatter.Registry { return chatter.Registry{} }
+
+func (r *AnswerRegistry) Invoke(reply *chatter.Reply) (thinker.Phase, chatter.Message, error) {
+	answer := &chatter.Answer{Yield: make([]chatter.Json, 0)}
+	for _, c := range reply.Content {
+		if inv, ok := c.(chatter.Invoke); ok {
+			answer.Yield = append(answer.Yield, chatter.Json{ID: inv.Args.ID, Source: inv.Cmd, Value: []byte(`{"toolOutput":"42"}`)})
+		}
+	}
+	return thinker.AGENT_ASK, answer, nil
+}
+
 //------------------------------------------------------------------------------
 // Test Manifold Memory
 //------------------------------------------------------------------------------
@@ -494,4 +578,187 @@ func TestManifoldMemory(t *testing.T) {
 		// The LLM_INVOKE reply was committed before the abort
 		it.Then(t).Should(it.Equal(len(mem.Context(nil)), 2))
 	})
+
+	// SessionIsolation verifies that a single manifold backed by session memory
+	// keeps the conversation of each session apart.
+	t.Run("SessionIsolation", func(t *testing.T) {
+		mem := memory.NewSessions(0, 0, func(string) thinker.Memory { return memory.NewStream(-1, "") })
+		manifold := agent.NewManifold(&Mock{}, codec.String, codec.String, &MockRegistry{}).WithMemory(mem)
+
+		a := memory.WithSession(context.Background(), "a")
+		b := memory.WithSession(context.Background(), "b")
+
+		_, err := manifold.Prompt(a, "First")
+		it.Then(t).Must(it.Nil(err))
+
+		result, err := manifold.Prompt(b, "Second")
+		it.Then(t).Must(it.Nil(err))
+		it.Then(t).ShouldNot(it.String(result).Contain("First"))
+
+		result, err = manifold.Prompt(a, "Third")
+		it.Then(t).Must(it.Nil(err))
+		it.Then(t).Should(
+			it.String(result).Contain("First"),
+			it.Equal(len(mem.Session(a).Context(nil)), 4),
+			it.Equal(len(mem.Session(b).Context(nil)), 2),
+		)
+	})
+}
+
+//------------------------------------------------------------------------------
+// Test Manifold Steps
+//------------------------------------------------------------------------------
+
+func TestManifoldSteps(t *testing.T) {
+	t.Run("MaxSteps", func(t *testing.T) {
+		llm := &RepeatMock{args: []string{`{"q":1}`, `{"q":2}`, `{"q":3}`, `{"q":4}`, `{"q":5}`}}
+		manifold := agent.NewManifold(llm, codec.String, codec.String, &CountRegistry{}).
+			WithMaxSteps(4)
+
+		_, err := manifold.Prompt(context.Background(), "input")
+
+		var e *thinker.StepsError
+		it.Then(t).Must(it.True(errors.As(err, &e)))
+		it.Then(t).Should(
+			it.Equal(e.Steps, 4),
+			it.Equal(e.Tool, "search"),
+			it.True(errors.Is(err, thinker.ErrMaxSteps)),
+			it.True(errors.Is(err, thinker.ErrAborted)),
+			it.Equal(llm.calls, 4),
+		)
+	})
+
+	t.Run("Unbounded", func(t *testing.T) {
+		manifold := agent.NewManifold(&InvokeThenReturnMock{}, codec.String, codec.String, &LoopRegistry{})
+
+		_, err := manifold.Prompt(context.Background(), "input")
+		it.Then(t).Should(it.Nil(err))
+	})
+
+	t.Run("Loop", func(t *testing.T) {
+		llm := &RepeatMock{args: []string{`{"a":1,"b":2}`, `{"b":2, "a":1}`}}
+		registry := &CountRegistry{}
+		mem := memory.NewStream(-1, "")
+		manifold := agent.NewManifold(llm, codec.String, codec.String, registry).
+			WithMemory(mem).
+			WithMaxRepeats(2)
+
+		_, err := manifold.Prompt(context.Background(), "input")
+
+		var e *thinker.StepsError
+		it.Then(t).Must(it.True(errors.As(err, &e)))
+		it.Then(t).Should(
+			it.Equal(e.Steps, 4),
+			it.Equal(e.Tool, "search"),
+			it.True(errors.Is(err, thinker.ErrToolLoop)),
+			it.Equal(registry.calls, 2),
+			it.Equal(llm.calls, 4),
+		)
+
+		// the corrective feedback is the answer to the third call
+		var feedback *chatter.Answer
+		for e := range mem.Observations() {
+			if a, ok := e.Query.Content.(*chatter.Answer); ok {
+				feedback = a
+			}
+		}
+		it.Then(t).Must(it.True(feedback != nil))
+		it.Then(t).Should(
+			it.Equal(feedback.Yield[0].ID, "id"),
+			it.String(string(feedback.Yield[0].Value)).Contain("3 times in a row"),
+		)
+	})
+
+	t.Run("LoopBroken", func(t *testing.T) {
+		llm := &RepeatMock{args: []string{`{"q":1}`, `{"q":1}`, `{"q":2}`, `{"q":2}`, `{"q":3}`}}
+		registry := &CountRegistry{}
+		manifold := agent.NewManifold(llm, codec.String, codec.String, registry).
+			WithMaxRepeats(2).
+			WithMaxSteps(5)
+
+		_, err := manifold.Prompt(context.Background(), "input")
+		it.Then(t).Should(
+			it.True(errors.Is(err, thinker.ErrMaxSteps)),
+			it.Equal(registry.calls, 5),
+		)
+	})
+}
+
+//------------------------------------------------------------------------------
+// Test Manifold Reasoner
+//------------------------------------------------------------------------------
+
+func TestManifoldReasoner(t *testing.T) {
+	t.Run("Epoch", func(t *testing.T) {
+		llm := &ToolThenReturnMock{}
+		refine := reasoner.From(func(state thinker.State[string]) (thinker.Phase, chatter.Message, error) {
+			return thinker.AGENT_REFINE, chatter.Text("refine"), nil
+		})
+		manifold := agent.NewManifold(llm, codec.String, codec.String, &AnswerRegistry{}).
+			WithReasoner(reasoner.NewEpoch(3, refine))
+
+		_, err := manifold.Prompt(context.Background(), "input")
+		it.Then(t).Should(
+			it.True(errors.Is(err, thinker.ErrAborted)),
+			it.Equal(llm.calls, 3),
+		)
+	})
+
+	t.Run("Tools", func(t *testing.T) {
+		var state thinker.State[string]
+		manifold := agent.NewManifold(&ToolThenReturnMock{}, codec.String, codec.String, &AnswerRegistry{}).
+			WithReasoner(reasoner.From(func(s thinker.State[string]) (thinker.Phase, chatter.Message, error) {
+				state = s
+				return thinker.AGENT_RETURN, nil, nil
+			}))
+
+		_, err := manifold.Prompt(context.Background(), "input")
+		it.Then(t).Must(it.Nil(err))
+		it.Then(t).Must(it.Equal(len(state.Tools), 1))
+		it.Then(t).Should(
+			it.Equal(state.Epoch, 2),
+			it.Equal(state.Tools[0].Epoch, 1),
+			it.Equal(state.Tools[0].Tool, "search"),
+			it.Equal(string(state.Tools[0].Args), `{"q":1}`),
+			it.Equal(string(state.Tools[0].Result), `{"toolOutput":"42"}`),
+		)
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		llm := &ContextMock{}
+		mem := memory.NewStream(-1, "")
+		manifold := agent.NewManifold(llm, codec.String, codec.String, &AnswerRegistry{}).
+			WithMemory(mem).
+			WithReasoner(reasoner.From(func(s thinker.State[string]) (thinker.Phase, chatter.Message, error) {
+				if s.Epoch < 3 {
+					return thinker.AGENT_RETRY, nil, nil
+				}
+				return thinker.AGENT_RETURN, nil, nil
+			}))
+
+		_, err := manifold.Prompt(context.Background(), "input")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(llm.seq).Equal(1, 1, 1),
+			it.Equal(mem.Len(), 1),
+		)
+	})
+
+	t.Run("Refine", func(t *testing.T) {
+		llm := &ToolThenReturnMock{}
+		manifold := agent.NewManifold(llm, codec.String, codec.String, &AnswerRegistry{}).
+			WithReasoner(reasoner.From(func(s thinker.State[string]) (thinker.Phase, chatter.Message, error) {
+				if s.Epoch < 3 {
+					return thinker.AGENT_REFINE, chatter.Text("refine"), nil
+				}
+				return thinker.AGENT_RETURN, nil, nil
+			}))
+
+		reply, err := manifold.Prompt(context.Background(), "input")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(reply, "refine"),
+			it.Equal(llm.calls, 3),
+		)
+	})
 }
@@ -9,10 +9,13 @@
 package nanobot
 
 import (
+	"context"
+
 	"github.com/kshard/chatter"
 	"github.com/kshard/thinker"
 	"github.com/kshard/thinker/agent"
 	"github.com/kshard/thinker/codec"
+	"github.com/kshard/thinker/ledger"
 	"github.com/kshard/thinker/memory"
 	"github.com/kshard/thinker/prompt/jsonify"
 	"github.com/kshard/thinker/reasoner"
@@ -54,14 +57,19 @@ func NewJsonify[A any](
 		codec.FromDecoder(w.decode),
 
 		// Configures the reasoner, which determines the agent's next actions and prompts.
-		// Here, we use a sequence of command reasoner, it assumes that input prompt is
-		// the workflow based on command. LLM guided to execute entire workflow.
-		reasoner.NewEpoch(attempts, reasoner.From(w.deduct)),
+		// Here, we use the threshold reasoner, it refines the request with the
+		// decoder's feedback until the result is valid.
+		reasoner.NewEpoch(attempts, reasoner.NewThreshold[[]string](1.0).WithAttempts(attempts)),
 	)
 
 	return w
 }
 
+// Prompt agent, the usage of tokens is accounted to jsonify (see ledger).
+func (w *Jsonify[A]) Prompt(ctx context.Context, input A, opt ...chatter.Opt) ([]string, error) {
+	return w.Automata.Prompt(ledger.WithAgent(ctx, "jsonify"), input, opt...)
+}
+
 func (w *Jsonify[A]) encode(in A) (chatter.Message, error) {
 	prompt, err := w.encoder.Encode(in)
 	if err != nil {
@@ -88,16 +96,3 @@ func (w *Jsonify[A]) decode(reply *chatter.Reply) (float64, []string, error) {
 
 	return 1.0, seq, nil
 }
-
-func (w *Jsonify[A]) deduct(state thinker.State[[]string]) (thinker.Phase, chatter.Message, error) {
-	// Provide feedback to LLM if there are no confidence about the results
-	if state.Feedback != nil && state.Confidence < 1.0 {
-		var prompt chatter.Prompt
-		prompt.WithTask("Refine the previous request using the feedback below.")
-		prompt.With(state.Feedback)
-		return thinker.AGENT_REFINE, &prompt, nil
-	}
-
-	// We have sufficient confidence, return results
-	return thinker.AGENT_RETURN, nil, nil
-}
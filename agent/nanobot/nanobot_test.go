@@ -11,14 +11,21 @@ package nanobot_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"testing/fstest"
 
 	"github.com/fogfish/it/v2"
 	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
 	"github.com/kshard/thinker/agent/nanobot"
 	"github.com/kshard/thinker/codec"
 	"github.com/kshard/thinker/command"
+	"github.com/kshard/thinker/ledger"
+	"github.com/kshard/thinker/memory"
+	"github.com/kshard/thinker/reasoner"
+	"github.com/kshard/thinker/tracer"
 )
 
 // =============================================================================
@@ -37,6 +44,7 @@ func (m *MockBot[S, A]) Prompt(ctx context.Context, input S, opt ...chatter.Opt)
 // MockChatter is a configurable mock for the chatter.Chatter interface used by Jsonify.
 type MockChatter struct {
 	response string
+	usage    chatter.Usage
 	err      error
 }
 
@@ -48,6 +56,7 @@ func (m *MockChatter) Prompt(_ context.Context, _ []chatter.Message, _ ...chatte
 	}
 	return &chatter.Reply{
 		Stage:   chatter.LLM_RETURN,
+		Usage:   m.usage,
 		Content: []chatter.Content{chatter.Text(m.response)},
 	}, nil
 }
@@ -110,6 +119,31 @@ func TestRuntime(t *testing.T) {
 		it.Then(t).ShouldNot(it.Nil(rt2))
 		it.Then(t).ShouldNot(it.Equal(rt, rt2))
 	})
+
+	t.Run("WithSpend", func(t *testing.T) {
+		llm := &MockChatter{response: "ok", usage: chatter.Usage{InputTokens: 60, ReplyTokens: 40}}
+		spend := reasoner.NewSpend(reasoner.Limits{Tokens: 150})
+		rt := nanobot.NewRuntime(nil, &MockLLMs{models: map[string]chatter.Chatter{"base": llm}}).
+			WithSpend(spend)
+
+		a, ok := rt.LLMs.Model("base")
+		it.Then(t).Must(it.True(ok))
+		b, _ := rt.LLMs.Model("base")
+
+		_, err := a.Prompt(context.Background(), []chatter.Message{chatter.Text("a")})
+		it.Then(t).Must(it.Nil(err))
+		_, err = b.Prompt(context.Background(), []chatter.Message{chatter.Text("b")})
+		it.Then(t).Must(it.Nil(err))
+
+		_, err = a.Prompt(context.Background(), []chatter.Message{chatter.Text("c")})
+		it.Then(t).Should(
+			it.True(errors.Is(err, thinker.ErrAborted)),
+			it.Equal(spend.Report().InputTokens, 120),
+		)
+
+		_, ok = rt.LLMs.Model("unknown")
+		it.Then(t).ShouldNot(it.True(ok))
+	})
 }
 
 // =============================================================================
@@ -141,6 +175,35 @@ func TestSeq(t *testing.T) {
 		)
 	})
 
+	t.Run("Traced", func(t *testing.T) {
+		mem := tracer.NewMemory()
+		ctx := tracer.New(mem).Context(context.Background())
+
+		errB := errors.New("bot-b error")
+		botA := &MockBot[Work, string]{
+			fn: func(_ context.Context, _ Work, _ ...chatter.Opt) (string, error) {
+				return "from-a", nil
+			},
+		}
+		botB := &MockBot[Work, string]{
+			fn: func(_ context.Context, _ Work, _ ...chatter.Opt) (string, error) {
+				return "", errB
+			},
+		}
+
+		_, err := nanobot.Seq(nanobot.Arrow(botA), nanobot.Arrow(botB)).Prompt(ctx, Work{})
+		it.Then(t).ShouldNot(it.Nil(err))
+
+		spans := mem.Lookup("nanobot.step")
+		it.Then(t).Must(it.Equal(len(spans), 2))
+		it.Then(t).Should(
+			it.Equal(spans[0].Attrs["step"], any(1)),
+			it.Equal(spans[0].Error, ""),
+			it.Equal(spans[1].Attrs["step"], any(2)),
+			it.Equal(spans[1].Error, errB.Error()),
+		)
+	})
+
 	t.Run("BotAFails", func(t *testing.T) {
 		errA := errors.New("bot-a error")
 		botA := &MockBot[Work, string]{
@@ -653,6 +716,36 @@ func TestThinkReAct(t *testing.T) {
 		)
 	})
 
+	t.Run("Ledger", func(t *testing.T) {
+		tokens := map[string]int{"task-1": 60, "task-2": 100, "task-3": 840}
+		think := &MockBot[Work, []TaskState]{
+			fn: func(_ context.Context, _ Work, _ ...chatter.Opt) ([]TaskState, error) {
+				return []TaskState{{Value: "task-1"}, {Value: "task-2"}, {Value: "task-3"}}, nil
+			},
+		}
+		react := nanobot.Arr[TaskState](func(ctx context.Context, t TaskState, _ ...chatter.Opt) (TaskState, error) {
+			llm := &MockChatter{response: `["done"]`, usage: chatter.Usage{InputTokens: tokens[t.Value]}}
+			_, err := nanobot.NewJsonify[string](llm, 3, codec.EncoderID, func([]string) error { return nil }).
+				Prompt(ctx, t.Value)
+			return t, err
+		})
+
+		bot := nanobot.ThinkReAct(rt, think, react, func(s Work, _ []TaskState) Work { return s })
+		seq := nanobot.Seq(nanobot.Lift(func(_ context.Context, s Work) (Work, error) { return s, nil }), bot.Prompt)
+
+		l := ledger.New()
+		_, err := seq.Prompt(l.Context(context.Background()), Work{})
+		it.Then(t).Must(it.Nil(err))
+
+		report := l.Report()
+		it.Then(t).Should(
+			it.Equal(report.Total.Tokens(), 1000),
+			it.Equal(report.ByStep()[0].Key, "step 2/task 3"),
+			it.Equal(report.ByStep()[0].Ratio, 0.84),
+			it.Equal(report.ByAgent()[0].Key, "jsonify"),
+		)
+	})
+
 	t.Run("ThinkFails", func(t *testing.T) {
 		errThink := errors.New("think error")
 		think := &MockBot[Work, []TaskState]{
@@ -920,6 +1013,22 @@ func TestReActWithTask(t *testing.T) {
 		)
 	})
 
+	t.Run("Traced", func(t *testing.T) {
+		mem := tracer.NewMemory()
+		ctx := tracer.New(mem).Context(context.Background())
+		bot := newBot(t, &MockChatter{response: "final answer"})
+
+		_, err := bot.Prompt(ctx, Work{Result: "input"})
+		it.Then(t).Must(it.Nil(err))
+
+		spans := mem.Lookup("nanobot.react")
+		it.Then(t).Must(it.Equal(len(spans), 1))
+		it.Then(t).Should(
+			it.Equal(spans[0].Attrs["model"], any("base")),
+			it.Equal(mem.Lookup("agent.manifold")[0].Parent, spans[0].ID),
+		)
+	})
+
 	t.Run("FailureCallsFail", func(t *testing.T) {
 		errLLM := errors.New("llm failure")
 		chalk := &MockChalk{}
@@ -939,3 +1048,117 @@ func TestReActWithTask(t *testing.T) {
 		)
 	})
 }
+
+// =============================================================================
+// TestReActSessions
+// =============================================================================
+
+// JSONAfterFeedback replies with the invalid JSON to the fresh conversation,
+// and with the valid one once the conversation contains the feedback.
+type JSONAfterFeedback struct{}
+
+func (JSONAfterFeedback) Usage() chatter.Usage { return chatter.Usage{} }
+
+func (JSONAfterFeedback) Prompt(_ context.Context, prompt []chatter.Message, _ ...chatter.Opt) (*chatter.Reply, error) {
+	text := "no json"
+	if len(prompt) > 1 {
+		text = `["ok"]`
+	}
+	return &chatter.Reply{Stage: chatter.LLM_RETURN, Content: []chatter.Content{chatter.Text(text)}}, nil
+}
+
+func TestReActSessions(t *testing.T) {
+	fs := fstest.MapFS{
+		"react.prompt": &fstest.MapFile{
+			Data: []byte("---\nformat: json\nretry: 2\n---\nReturn {{.Result}}"),
+		},
+	}
+
+	mem := memory.NewSessions(0, 0, func(string) thinker.Memory { return memory.NewStream(-1, "") })
+	bot, err := nanobot.NewReAct[Work, []string](nanobot.NewRuntime(fs, &MockLLMs{
+		models: map[string]chatter.Chatter{"base": JSONAfterFeedback{}},
+	}), "react.prompt")
+	it.Then(t).Must(it.Nil(err))
+	bot = bot.WithMemory(mem)
+
+	const n = 16
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			ctx := memory.WithSession(context.Background(), fmt.Sprintf("session %d", i))
+			_, errs[i] = bot.Prompt(ctx, Work{Result: "input"})
+		})
+	}
+	wg.Wait()
+
+	it.Then(t).Should(
+		it.Seq(errs).Equal(make([]error, n)...),
+		it.Equal(mem.Len(), n),
+	)
+}
+
+// ContextLength replies with the number of messages in the conversation
+type ContextLength struct{}
+
+func (ContextLength) Usage() chatter.Usage { return chatter.Usage{} }
+
+func (ContextLength) Prompt(_ context.Context, prompt []chatter.Message, _ ...chatter.Opt) (*chatter.Reply, error) {
+	text := fmt.Sprintf(`["%d"]`, len(prompt))
+	return &chatter.Reply{Stage: chatter.LLM_RETURN, Content: []chatter.Content{chatter.Text(text)}}, nil
+}
+
+func TestReActIsolation(t *testing.T) {
+	fs := fstest.MapFS{
+		"react.prompt": &fstest.MapFile{
+			Data: []byte("---\nformat: json\n---\nReturn {{.Result}}"),
+		},
+	}
+
+	bot, err := nanobot.NewReAct[Work, []string](nanobot.NewRuntime(fs, &MockLLMs{
+		models: map[string]chatter.Chatter{"base": ContextLength{}},
+	}), "react.prompt")
+	it.Then(t).Must(it.Nil(err))
+
+	const n = 16
+	vals := make([][]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			vals[i], errs[i] = bot.Prompt(context.Background(), Work{Result: "input"})
+		})
+	}
+	wg.Wait()
+
+	seq := make([][]string, n)
+	for i := range seq {
+		seq[i] = []string{"1"}
+	}
+
+	it.Then(t).Should(
+		it.Seq(errs).Equal(make([]error, n)...),
+		it.Seq(vals).Equal(seq...),
+	)
+}
+
+func TestReActRetry(t *testing.T) {
+	fs := fstest.MapFS{
+		"react.prompt": &fstest.MapFile{
+			Data: []byte("---\nformat: json\nretry: 2\n---\nReturn {{.Result}}"),
+		},
+	}
+
+	bot, err := nanobot.NewReAct[Work, []string](nanobot.NewRuntime(fs, &MockLLMs{
+		models: map[string]chatter.Chatter{"base": &MockChatter{response: "no json"}},
+	}), "react.prompt")
+	it.Then(t).Must(it.Nil(err))
+
+	for range 2 {
+		_, err = bot.Prompt(context.Background(), Work{Result: "input"})
+		it.Then(t).ShouldNot(it.Nil(err))
+		it.Then(t).Should(
+			it.String(err.Error()).Contain("after 2 attempts"),
+		)
+	}
+}
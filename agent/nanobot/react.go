@@ -11,7 +11,9 @@ package nanobot
 import (
 	"context"
 	"encoding"
+	"errors"
 	"fmt"
+	"iter"
 	"os"
 	"reflect"
 	"strings"
@@ -23,6 +25,7 @@ import (
 	"github.com/kshard/thinker/agent"
 	"github.com/kshard/thinker/codec"
 	"github.com/kshard/thinker/command"
+	"github.com/kshard/thinker/ledger"
 	"github.com/kshard/thinker/memory"
 	"github.com/kshard/thinker/prompt"
 	"github.com/kshard/thinker/prompt/jsonify"
@@ -35,9 +38,8 @@ import (
 // Prompt method that drives the full BotReAct cycle.
 type BotReAct[A, B any] struct {
 	manifold *agent.Manifold[A, B]
-	attempt  int
-	external bool
-	memory   thinker.Memory
+	file     string
+	model    string
 	registry *command.SeqRegistry
 	prompt   *prompt.Prompt
 	t        *template.Template
@@ -70,8 +72,10 @@ func NewReAct[A, B any](rt *Runtime, file string) (*BotReAct[A, B], error) {
 	}
 
 	const base = "base"
-	runner, ok := rt.LLMs.Model(prompt.RunsOn)
+	model := prompt.RunsOn
+	runner, ok := rt.LLMs.Model(model)
 	if !ok && prompt.RunsOn != base {
+		model = base
 		runner, _ = rt.LLMs.Model(base)
 	}
 
@@ -100,26 +104,29 @@ func NewReAct[A, B any](rt *Runtime, file string) (*BotReAct[A, B], error) {
 		runner = aio.NewJsonLogger(os.Stderr, runner)
 	}
 
-	bot := &BotReAct[A, B]{prompt: prompt, t: t}
+	bot := &BotReAct[A, B]{file: file, model: model, prompt: prompt, t: t}
 
 	bot.registry = command.NewSeqRegistry()
 	bot.registry.Bind(registry)
 	bot.registry.Bind(rt.Registry)
 
-	bot.memory = memory.NewStream(-1, "")
-
 	bot.manifold = agent.NewManifold(
 		runner,
 		codec.FromEncoder(bot.encode),
 		codec.FromDecoder(bot.decode),
 		bot.registry,
-	).WithMemory(bot.memory)
+	).WithMemory(runMemory{Memory: memory.NewVoid("")}).
+		WithReasoner(&attempts[B]{retry: prompt.Retry}).
+		WithRetry(rt.Retry).
+		WithMaxSteps(prompt.Steps).
+		WithMaxRepeats(prompt.Repeats)
 
 	return bot, nil
 }
 
+// WithMemory replaces the memory of the bot, the memory is shared by runs of
+// the bot. By default, every run has own empty memory.
 func (bot *BotReAct[A, B]) WithMemory(memory thinker.Memory) *BotReAct[A, B] {
-	bot.memory, bot.external = memory, true
 	bot.manifold = bot.manifold.WithMemory(memory)
 	return bot
 }
@@ -144,31 +151,68 @@ func (bot *BotReAct[A, B]) WithTaskf(taskf func(A) string, donef ...func(B) stri
 // Prompt encodes the input using the prompt template, runs the Manifold
 // ReAct loop until the model returns a final answer, and decodes the result
 // into B. Progress is reported via the Chalk sink when the prompt file
-// declares a name.
+// declares a name. The run is traced as the span, annotated with the model.
+// The usage of tokens is accounted to the model and the prompt file (see ledger).
 func (bot *BotReAct[A, B]) Prompt(ctx context.Context, input A, opt ...chatter.Opt) (B, error) {
-	if !bot.external {
-		bot.memory.Reset()
+	ctx = bot.scope(ctx)
+	ctx, span := thinker.StartSpan(ctx, "nanobot.react")
+	span.With("model", bot.model)
+
+	done := bot.start(ctx, input)
+	val, err := bot.manifold.Prompt(ctx, input, opt...)
+	done(val, err)
+	span.End(err)
+	return val, err
+}
+
+// Stream is the streaming variant of Prompt, it yields events of the Manifold
+// ReAct loop, including tool invocations.
+func (bot *BotReAct[A, B]) Stream(ctx context.Context, input A, opt ...chatter.Opt) iter.Seq2[agent.Event[B], error] {
+	return func(yield func(agent.Event[B], error) bool) {
+		ctx := bot.scope(ctx)
+		ctx, span := thinker.StartSpan(ctx, "nanobot.react")
+		span.With("model", bot.model)
+
+		var failed error
+		defer func() { span.End(failed) }()
+
+		done := bot.start(ctx, input)
+		for evt, err := range bot.manifold.Stream(ctx, input, opt...) {
+			if err != nil || evt.Kind == agent.EVENT_RETURN {
+				failed = err
+				done(evt.Value, err)
+			}
+
+			if !yield(evt, err) {
+				return
+			}
+		}
 	}
+}
 
+// starts the run, it reports the task to the Chalk sink. The returned
+// function reports the outcome of the run.
+func (bot *BotReAct[A, B]) start(ctx context.Context, input A) func(B, error) {
 	chalk, ok := ctx.Value(chalkboard).(Chalk)
 	if !ok || chalk == nil || bot.taskf == nil {
-		return bot.manifold.Prompt(ctx, input, opt...)
+		return func(B, error) {}
 	}
 
 	chalk.Task(ctx, bot.taskf(input))
-	val, err := bot.manifold.Prompt(ctx, input, opt...)
-	if err != nil {
-		chalk.Fail(err)
-		return val, err
-	}
-
-	if bot.donef != nil {
-		chalk.Done(bot.donef(val))
-	} else {
-		chalk.Done()
+	return func(val B, err error) {
+		switch {
+		case err != nil:
+			chalk.Fail(err)
+		case bot.donef != nil:
+			chalk.Done(bot.donef(val))
+		default:
+			chalk.Done()
+		}
 	}
+}
 
-	return val, nil
+func (bot *BotReAct[A, B]) scope(ctx context.Context) context.Context {
+	return ledger.WithAgent(ledger.WithModel(ctx, bot.model), bot.file)
 }
 
 func (bot *BotReAct[A, B]) encode(in A) (chatter.Message, error) {
@@ -192,7 +236,6 @@ func (bot *BotReAct[A, B]) encode(in A) (chatter.Message, error) {
 		jsonify.Strings.Harden(&prompt, bot.prompt.Schema.Reply)
 	}
 
-	bot.attempt = 0
 	return &prompt, nil
 }
 
@@ -220,16 +263,51 @@ func (bot *BotReAct[A, B]) decode(reply *chatter.Reply) (float64, B, error) {
 
 	var out B
 	if err := jsonify.Strings.Decode(reply, bot.prompt.Schema.Reply, &out); err != nil {
-		bot.attempt++
-		if bot.attempt >= bot.prompt.Retry {
-			return 0.0, out, fmt.Errorf("unable to reply with JSON after %d attempts: %w", bot.attempt, err)
-		}
 		return 0.0, out, err
 	}
 
 	return 1.0, out, nil
 }
 
+// runMemory is the default memory of ReAct agents, every run of the agent
+// is bound to own empty stream memory, concurrent runs are isolated.
+type runMemory struct{ thinker.Memory }
+
+func (runMemory) Session(context.Context) thinker.Memory {
+	return memory.NewStream(memory.INFINITE, "")
+}
+
+// attempts is the reasoner of ReAct agents, it refines the reply with
+// the feedback of the decoder until the retry budget is exhausted. It is
+// forked for every run, concurrent runs have own budget.
+type attempts[B any] struct {
+	retry   int
+	attempt int
+}
+
+func (r *attempts[B]) Fork() thinker.Reasoner[B] { return &attempts[B]{retry: r.retry} }
+
+func (r *attempts[B]) Purge() { r.attempt = 0 }
+
+func (r *attempts[B]) Deduct(state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
+	if state.Feedback == nil {
+		return thinker.AGENT_RETURN, nil, nil
+	}
+
+	r.attempt++
+	if r.attempt >= r.retry {
+		err, ok := state.Feedback.(error)
+		if !ok {
+			err = errors.New(state.Feedback.String())
+		}
+		return thinker.AGENT_ABORT, nil, fmt.Errorf("unable to reply with JSON after %d attempts: %w", r.attempt, err)
+	}
+
+	var prompt chatter.Prompt
+	prompt.With(state.Feedback)
+	return thinker.AGENT_REFINE, &prompt, nil
+}
+
 // see https://github.com/google/jsonschema-go/issues/23 for details
 // func (bot *NanoBot[A, B]) validateSchema(obj any, schema *jsonschema.Schema) error {
 // 	resolved, err := schema.Resolve(nil)
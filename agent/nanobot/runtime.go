@@ -15,7 +15,10 @@ import (
 
 	"github.com/fogfish/golem/optics"
 	"github.com/kshard/chatter"
+	"github.com/kshard/thinker/agent"
 	"github.com/kshard/thinker/command"
+	"github.com/kshard/thinker/ledger"
+	"github.com/kshard/thinker/reasoner"
 )
 
 // LLMs is the registry of available language models. Callers look up a model
@@ -46,11 +49,12 @@ type Bot[S, A any] interface {
 // Runtime is the shared execution environment threaded through every agent
 // constructor. It bundles the file system (for prompt templates), the LLM
 // registry, an optional command registry for tool use, and the progress
-// reporter.
+// reporter, and the retry policy of transient LLM errors.
 type Runtime struct {
 	FileSystem fs.FS
 	LLMs       LLMs
 	Registry   *command.Registry
+	Retry      agent.Retry
 }
 
 // NewRuntime creates a Runtime with the given file system and LLM registry.
@@ -70,6 +74,7 @@ func (rt *Runtime) WithFileSystem(fs fs.FS) *Runtime {
 		FileSystem: fs,
 		LLMs:       rt.LLMs,
 		Registry:   rt.Registry,
+		Retry:      rt.Retry,
 	}
 }
 
@@ -81,9 +86,46 @@ func (rt *Runtime) WithRegistry(r *command.Registry) *Runtime {
 		FileSystem: rt.FileSystem,
 		LLMs:       rt.LLMs,
 		Registry:   r,
+		Retry:      rt.Retry,
 	}
 }
 
+// WithSpend returns a copy of the runtime that meters every model of the LLM
+// registry using the spend. Agents sharing the runtime share the spend
+// ceiling, the LLM call fails once any limit is reached.
+func (rt *Runtime) WithSpend(spend *reasoner.Spend) *Runtime {
+	return &Runtime{
+		FileSystem: rt.FileSystem,
+		LLMs:       meteredLLMs{LLMs: rt.LLMs, spend: spend},
+		Registry:   rt.Registry,
+		Retry:      rt.Retry,
+	}
+}
+
+// WithRetry returns a copy of the runtime that uses the given retry policy
+// of transient LLM errors. Each agent will inherit it.
+func (rt *Runtime) WithRetry(retry agent.Retry) *Runtime {
+	return &Runtime{
+		FileSystem: rt.FileSystem,
+		LLMs:       rt.LLMs,
+		Registry:   rt.Registry,
+		Retry:      retry,
+	}
+}
+
+type meteredLLMs struct {
+	LLMs
+	spend *reasoner.Spend
+}
+
+func (m meteredLLMs) Model(name string) (chatter.Chatter, bool) {
+	llm, ok := m.LLMs.Model(name)
+	if !ok {
+		return nil, false
+	}
+	return m.spend.Meter(name, llm), true
+}
+
 // =============================================================================
 // Kleisli State Algebra
 //
@@ -192,14 +234,22 @@ func (f Arr[S]) WithTask(name string) Arr[S] { return f.WithTaskf(func(S) string
 // WithTaskf wraps the arrow with a progress report.
 // The task name is generated by applying fn to the current state S at the time of execution.
 // The task is automatically marked done when the arrow returns, even if it returns an error.
+// The usage of tokens is accounted to the task (see ledger).
 func (f Arr[S]) WithTaskf(fn func(S) string) Arr[S] {
 	return func(ctx context.Context, s S, opt ...chatter.Opt) (S, error) {
+		if fn == nil {
+			return f(ctx, s, opt...)
+		}
+
+		task := fn(s)
+		ctx = ledger.WithStep(ctx, task)
+
 		c, ok := ctx.Value(chalkboard).(Chalk)
-		if !ok || c == nil || fn == nil {
+		if !ok || c == nil {
 			return f(ctx, s, opt...)
 		}
 
-		c.Task(ctx, fn(s))
+		c.Task(ctx, task)
 		defer c.Done()
 		return f(c.Sub(ctx), s, opt...)
 	}
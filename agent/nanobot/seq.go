@@ -10,8 +10,11 @@ package nanobot
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/ledger"
 )
 
 // Seq composes Kleisli arrows left-to-right over a shared state S.
@@ -26,11 +29,20 @@ import (
 //
 // Individual arrows are constructed with Arrow, which lifts a Bot[S, A] into
 // Arr[S] by applying an Eff (Lens setter + Eval side-effect).
+//
+// Every step is traced as the span, annotated with the position of the step
+// counted from 1. The usage of tokens is accounted to the step under the same
+// number (e.g. "step 2", see ledger).
 func Seq[S any](steps ...Arr[S]) Arr[S] {
 	return func(ctx context.Context, s S, opt ...chatter.Opt) (S, error) {
-		for _, step := range steps {
+		for i, step := range steps {
+			sctx := ledger.WithStep(ctx, fmt.Sprintf("step %d", i+1))
+			sctx, span := thinker.StartSpan(sctx, "nanobot.step")
+			span.With("step", i+1)
+
 			var err error
-			s, err = step(ctx, s, opt...)
+			s, err = step(sctx, s, opt...)
+			span.End(err)
 			if err != nil {
 				return s, err
 			}
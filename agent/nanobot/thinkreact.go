@@ -10,8 +10,10 @@ package nanobot
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/kshard/chatter"
+	"github.com/kshard/thinker/ledger"
 )
 
 // Think lifts Bot[S, []A] into Bot[S, []T] by applying a scatter function
@@ -96,7 +98,8 @@ func NewThinkReAct[S, T any](rt *Runtime, think Bot[S, []T], react Arr[T], gathe
 
 // Prompt runs the full think-then-react cycle: calls the think bot once to
 // obtain the task list, then runs the react arrow on each task independently,
-// and gathers the results back into the outer state S.
+// and gathers the results back into the outer state S. The usage of tokens
+// is accounted to the task (e.g. "task 3", see ledger).
 func (bot *BotThinkReAct[S, T]) Prompt(ctx context.Context, input S, opt ...chatter.Opt) (S, error) {
 	tasks, err := bot.think.Prompt(ctx, input, opt...)
 	if err != nil {
@@ -105,7 +108,7 @@ func (bot *BotThinkReAct[S, T]) Prompt(ctx context.Context, input S, opt ...chat
 
 	results := make([]T, len(tasks))
 	for i, task := range tasks {
-		t, err := bot.react(ctx, task, opt...)
+		t, err := bot.react(ledger.WithStep(ctx, fmt.Sprintf("task %d", i+1)), task, opt...)
 		if err != nil {
 			return *new(S), err
 		}
@@ -0,0 +1,164 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/ledger"
+)
+
+// Retry policy of transient LLM errors (e.g. throttling), using exponential
+// backoff with jitter. The zero value disables retries.
+type Retry struct {
+	// Maximum number of attempts, including the first one
+	Attempts int
+
+	// Initial backoff delay, it doubles with each attempt
+	Backoff time.Duration
+
+	// Upper bound of the backoff delay, unbounded if zero
+	MaxBackoff time.Duration
+
+	// Classifies errors as retryable, Transient is used if nil
+	Retryable func(error) bool
+}
+
+// Default retry policy.
+var DefaultRetry = Retry{Attempts: 4, Backoff: 500 * time.Millisecond, MaxBackoff: 10 * time.Second}
+
+// Transient classifies errors as retryable if the LLM provider is throttling
+// (HTTP 429, throttling error codes), unavailable (HTTP 5xx) or the request
+// has timed out. Other errors are permanent (e.g. authentication, validation
+// or the context length), including cancellation of context and aborts of
+// execution (e.g. the spend limit is reached). The status is obtained from
+// the error using HTTPStatusCode(), StatusCode(), ErrorCode(), Timeout() or
+// Temporary() methods, as exposed by provider SDKs and the net package.
+func Transient(err error) bool {
+	if errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, thinker.ErrAborted) {
+		return false
+	}
+
+	var status interface{ HTTPStatusCode() int }
+	if errors.As(err, &status) {
+		return transientStatus(status.HTTPStatusCode())
+	}
+
+	var code interface{ StatusCode() int }
+	if errors.As(err, &code) {
+		return transientStatus(code.StatusCode())
+	}
+
+	var api interface{ ErrorCode() string }
+	if errors.As(err, &api) {
+		code := strings.ToLower(api.ErrorCode())
+		return strings.Contains(code, "throttl") ||
+			strings.Contains(code, "toomanyrequests") ||
+			strings.Contains(code, "unavailable") ||
+			strings.Contains(code, "internalserver")
+	}
+
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) && temporary.Temporary() {
+		return true
+	}
+
+	return false
+}
+
+func transientStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+}
+
+// prompts LLM, retrying transient errors
+func (r Retry) prompt(ctx context.Context, llm chatter.Chatter, seq []chatter.Message, opt ...chatter.Opt) (*chatter.Reply, error) {
+	return r.call(ctx, func() (*chatter.Reply, error) { return llm.Prompt(ctx, seq, opt...) })
+}
+
+// calls LLM, retrying transient errors. The call is traced as the single
+// span, including all attempts, annotated with the model of the ledger scope.
+func (r Retry) call(ctx context.Context, f func() (*chatter.Reply, error)) (reply *chatter.Reply, err error) {
+	_, span := thinker.StartSpan(ctx, "llm.prompt")
+	defer func() { span.End(err) }()
+
+	if model := ledger.ScopeOf(ctx).Model; model != "" {
+		span.With("model", model)
+	}
+
+	retryable := r.Retryable
+	if retryable == nil {
+		retryable = Transient
+	}
+
+	for attempt := 1; ; attempt++ {
+		span.With("attempts", attempt)
+		reply, err := f()
+		if err == nil {
+			span.With("stage", string(reply.Stage)).
+				With("tokens.input", reply.Usage.InputTokens).
+				With("tokens.reply", reply.Usage.ReplyTokens)
+			ledger.Account(ctx, reply.Usage)
+			return reply, nil
+		}
+
+		if attempt >= r.Attempts || errors.Is(err, errStopped) || !retryable(err) {
+			return nil, err
+		}
+
+		select {
+		case <-time.After(r.delay(attempt)):
+		case <-ctx.Done():
+			return nil, errors.Join(err, ctx.Err())
+		}
+	}
+}
+
+// scopes the usage of tokens to the agent, unless the context is already
+// scoped by the caller (e.g. nanobot scopes it to the prompt file), and to
+// the model of the agent, if it is configured
+func scope(ctx context.Context, agent, model string) context.Context {
+	if model != "" {
+		ctx = ledger.WithModel(ctx, model)
+	}
+	if ledger.ScopeOf(ctx).Agent != "" {
+		return ctx
+	}
+	return ledger.WithAgent(ctx, agent)
+}
+
+// equal jitter: half of the exponential delay is randomized
+func (r Retry) delay(attempt int) time.Duration {
+	if r.Backoff <= 0 {
+		return 0
+	}
+
+	d := r.Backoff << min(attempt-1, 30)
+	if r.MaxBackoff > 0 && (d <= 0 || d > r.MaxBackoff) {
+		d = r.MaxBackoff
+	}
+
+	if d < 2 {
+		return d
+	}
+	return d/2 + rand.N(d/2)
+}
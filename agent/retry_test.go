@@ -0,0 +1,184 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/agent"
+	"github.com/kshard/thinker/codec"
+	"github.com/kshard/thinker/memory"
+	"github.com/kshard/thinker/reasoner"
+)
+
+// Flaky fails N times before echoing the input.
+type Flaky struct {
+	Mock
+	failures int
+	err      error
+	calls    int
+}
+
+func (m *Flaky) Prompt(ctx context.Context, prompt []chatter.Message, opt ...chatter.Opt) (*chatter.Reply, error) {
+	m.calls++
+	if m.calls <= m.failures {
+		return nil, m.err
+	}
+	return m.Mock.Prompt(ctx, prompt, opt...)
+}
+
+// statusError is the error of LLM provider with HTTP status code.
+type statusError int
+
+func (e statusError) Error() string       { return fmt.Sprintf("status %d", int(e)) }
+func (e statusError) HTTPStatusCode() int { return int(e) }
+
+// codeError is the error of LLM provider with the error code.
+type codeError string
+
+func (e codeError) Error() string     { return string(e) }
+func (e codeError) ErrorCode() string { return string(e) }
+
+var errThrottled error = statusError(http.StatusTooManyRequests)
+
+//------------------------------------------------------------------------------
+// Test Retry
+//------------------------------------------------------------------------------
+
+func TestRetry(t *testing.T) {
+	retry := agent.Retry{Attempts: 3, Backoff: time.Millisecond}
+
+	automata := func(llm chatter.Chatter) *agent.Automata[string, string] {
+		return agent.NewAutomata(llm, memory.NewVoid(""), codec.String, codec.String, reasoner.NewVoid[string]())
+	}
+
+	manifold := func(llm chatter.Chatter) *agent.Manifold[string, string] {
+		return agent.NewManifold(llm, codec.String, codec.String, &MockRegistry{})
+	}
+
+	t.Run("AutomataRecovers", func(t *testing.T) {
+		llm := &Flaky{failures: 2, err: errThrottled}
+		_, err := automata(llm).WithRetry(retry).Prompt(context.Background(), "input")
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(llm.calls, 3),
+		)
+	})
+
+	t.Run("ManifoldRecovers", func(t *testing.T) {
+		llm := &Flaky{failures: 2, err: errThrottled}
+		_, err := manifold(llm).WithRetry(retry).Prompt(context.Background(), "input")
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(llm.calls, 3),
+		)
+	})
+
+	t.Run("MaxAttempts", func(t *testing.T) {
+		llm := &Flaky{failures: 3, err: errThrottled}
+		_, err := manifold(llm).WithRetry(retry).Prompt(context.Background(), "input")
+
+		it.Then(t).Should(
+			it.True(errors.Is(err, thinker.ErrLLM)),
+			it.True(errors.Is(err, errThrottled)),
+			it.Equal(llm.calls, 3),
+		)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		llm := &Flaky{failures: 1, err: errThrottled}
+		_, err := automata(llm).Prompt(context.Background(), "input")
+
+		it.Then(t).Should(
+			it.True(errors.Is(err, thinker.ErrLLM)),
+			it.Equal(llm.calls, 1),
+		)
+	})
+
+	t.Run("NotRetryable", func(t *testing.T) {
+		llm := &Flaky{failures: 2, err: thinker.ErrAborted}
+		_, err := automata(llm).WithRetry(retry).Prompt(context.Background(), "input")
+
+		it.Then(t).Should(
+			it.True(errors.Is(err, thinker.ErrAborted)),
+			it.Equal(llm.calls, 1),
+		)
+	})
+
+	t.Run("Permanent", func(t *testing.T) {
+		for _, e := range []error{
+			statusError(http.StatusUnauthorized),
+			statusError(http.StatusBadRequest),
+			codeError("ValidationException"),
+			errors.New("input is too long"),
+		} {
+			llm := &Flaky{failures: 2, err: e}
+			_, err := automata(llm).WithRetry(retry).Prompt(context.Background(), "input")
+
+			it.Then(t).Should(
+				it.True(errors.Is(err, e)),
+				it.Equal(llm.calls, 1),
+			)
+		}
+	})
+
+	t.Run("Transient", func(t *testing.T) {
+		for _, e := range []error{
+			statusError(http.StatusServiceUnavailable),
+			codeError("ThrottlingException"),
+			fmt.Errorf("wrapped: %w", statusError(http.StatusTooManyRequests)),
+		} {
+			llm := &Flaky{failures: 2, err: e}
+			_, err := automata(llm).WithRetry(retry).Prompt(context.Background(), "input")
+
+			it.Then(t).Should(
+				it.Nil(err),
+				it.Equal(llm.calls, 3),
+			)
+		}
+	})
+
+	t.Run("Classifier", func(t *testing.T) {
+		llm := &Flaky{failures: 2, err: errThrottled}
+		policy := retry
+		policy.Retryable = func(err error) bool { return !errors.Is(err, errThrottled) }
+		_, err := automata(llm).WithRetry(policy).Prompt(context.Background(), "input")
+
+		it.Then(t).Should(
+			it.True(errors.Is(err, errThrottled)),
+			it.Equal(llm.calls, 1),
+		)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		llm := &Flaky{failures: 10, err: errThrottled}
+		_, err := automata(llm).
+			WithRetry(agent.Retry{Attempts: 10, Backoff: time.Hour}).
+			Prompt(ctx, "input")
+
+		it.Then(t).Should(
+			it.True(errors.Is(err, context.Canceled)),
+			it.True(errors.Is(err, errThrottled)),
+			it.Equal(llm.calls, 1),
+		)
+	})
+}
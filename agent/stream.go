@@ -0,0 +1,138 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// Kind of the execution event
+type EventKind int
+
+const (
+	// Epoch is started, the event carries the prompt
+	EVENT_EPOCH EventKind = iota
+	// Partial text of the reply, if LLM streams (see Streamer)
+	EVENT_TOKEN
+	// Streaming attempt has failed and LLM is prompted again, partial text of
+	// the reply emitted since the last EVENT_EPOCH or EVENT_RETRY is discarded
+	EVENT_RETRY
+	// Reply from LLM is received
+	EVENT_REPLY
+	// Reply is decoded, the event carries the value, confidence and feedback
+	EVENT_DECODE
+	// Reasoner has decided the phase, the event carries the new prompt
+	EVENT_DECIDE
+	// Tool is invoked, the event carries the reply with the invocation
+	EVENT_INVOKE
+	// Tool has returned results, the event carries the phase and results
+	EVENT_ANSWER
+	// Final value is returned
+	EVENT_RETURN
+)
+
+// Event of the agent's execution.
+type Event[B any] struct {
+	Kind  EventKind
+	Epoch int
+
+	// Prompt of the epoch, new prompt decided by reasoner or results of the tool
+	Message chatter.Message
+
+	// Partial text of the reply
+	Token string
+
+	// Reply from LLM
+	Reply *chatter.Reply
+
+	// Decoded value, its confidence and feedback to LLM
+	Value      B
+	Confidence float64
+	Feedback   chatter.Content
+
+	// Decision of the reasoner or the registry
+	Phase thinker.Phase
+}
+
+// Streamer is the optional interface of LLM, it passes the partial text of
+// the reply to the callback as the text is generated.
+type Streamer interface {
+	chatter.Chatter
+	PromptStream(ctx context.Context, prompt []chatter.Message, token func(string), opt ...chatter.Opt) (*chatter.Reply, error)
+}
+
+// emits the event, the error stops the execution
+type emitter[B any] func(Event[B]) error
+
+func silent[B any](Event[B]) error { return nil }
+
+// the consumer has stopped the iteration
+var errStopped = errors.New("stream is stopped by consumer")
+
+// builds the stream of events from the execution
+func stream[B any](exec func(emitter[B]) (B, error)) iter.Seq2[Event[B], error] {
+	return func(yield func(Event[B], error) bool) {
+		value, err := exec(func(e Event[B]) error {
+			if !yield(e, nil) {
+				return errStopped
+			}
+			return nil
+		})
+
+		switch {
+		case errors.Is(err, errStopped):
+			return
+		case err != nil:
+			yield(Event[B]{}, err)
+		default:
+			yield(Event[B]{Kind: EVENT_RETURN, Value: value}, nil)
+		}
+	}
+}
+
+// prompts LLM, passing the partial text of the reply to the callback as
+// EVENT_TOKEN if LLM streams. The attempt that has streamed the text and
+// failed is followed by EVENT_RETRY so that the consumer discards the text.
+// The error of callback stops the call.
+func (r Retry) stream(ctx context.Context, llm chatter.Chatter, seq []chatter.Message, token func(EventKind, string) error, opt ...chatter.Opt) (*chatter.Reply, error) {
+	streamer, ok := llm.(Streamer)
+	if !ok {
+		return r.prompt(ctx, llm, seq, opt...)
+	}
+
+	streamed := false
+	return r.call(ctx, func() (*chatter.Reply, error) {
+		if streamed {
+			if err := token(EVENT_RETRY, ""); err != nil {
+				return nil, err
+			}
+			streamed = false
+		}
+
+		var stopped error
+		reply, err := streamer.PromptStream(ctx, seq,
+			func(text string) {
+				if stopped == nil {
+					streamed = true
+					stopped = token(EVENT_TOKEN, text)
+				}
+			},
+			opt...,
+		)
+		if stopped != nil {
+			return nil, stopped
+		}
+		return reply, err
+	})
+}
@@ -0,0 +1,202 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent_test
+
+import (
+	"context"
+	"iter"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/agent"
+	"github.com/kshard/thinker/codec"
+	"github.com/kshard/thinker/memory"
+	"github.com/kshard/thinker/reasoner"
+)
+
+// StreamMock echoes the input, passing it word by word to the callback.
+type StreamMock struct{ Mock }
+
+func (m *StreamMock) PromptStream(ctx context.Context, prompt []chatter.Message, token func(string), opt ...chatter.Opt) (*chatter.Reply, error) {
+	reply, err := m.Prompt(ctx, prompt, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, word := range strings.Fields(reply.String()) {
+		token(word)
+	}
+	return reply, nil
+}
+
+// FlakyStreamMock streams the first word of the reply and fails, the given
+// number of times, before it streams the reply.
+type FlakyStreamMock struct {
+	StreamMock
+	failures int
+}
+
+func (m *FlakyStreamMock) PromptStream(ctx context.Context, prompt []chatter.Message, token func(string), opt ...chatter.Opt) (*chatter.Reply, error) {
+	if m.failures > 0 {
+		m.failures--
+		token("partial")
+		return nil, errThrottled
+	}
+	return m.StreamMock.PromptStream(ctx, prompt, token, opt...)
+}
+
+// collects kinds of events, stops after n events if n > 0
+func kinds[B any](seq iter.Seq2[agent.Event[B], error], n int) ([]agent.EventKind, []agent.Event[B], error) {
+	var (
+		ks  []agent.EventKind
+		evs []agent.Event[B]
+	)
+	for evt, err := range seq {
+		if err != nil {
+			return ks, evs, err
+		}
+		ks = append(ks, evt.Kind)
+		evs = append(evs, evt)
+		if n > 0 && len(ks) == n {
+			break
+		}
+	}
+	return ks, evs, nil
+}
+
+//------------------------------------------------------------------------------
+// Test Stream
+//------------------------------------------------------------------------------
+
+func TestStream(t *testing.T) {
+	t.Run("Automata", func(t *testing.T) {
+		automata := agent.NewAutomata(&Mock{}, memory.NewVoid(""), codec.String, codec.String,
+			reasoner.From(func(state thinker.State[string]) (thinker.Phase, chatter.Message, error) {
+				if state.Epoch < 2 {
+					return thinker.AGENT_RETRY, nil, nil
+				}
+				return thinker.AGENT_RETURN, nil, nil
+			}),
+		)
+
+		ks, evs, err := kinds(automata.Stream(context.Background(), "input"), 0)
+		it.Then(t).Must(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Seq(ks).Equal(
+				agent.EVENT_EPOCH, agent.EVENT_REPLY, agent.EVENT_DECODE, agent.EVENT_DECIDE,
+				agent.EVENT_EPOCH, agent.EVENT_REPLY, agent.EVENT_DECODE, agent.EVENT_DECIDE,
+				agent.EVENT_RETURN,
+			),
+			it.Equal(evs[3].Phase, thinker.AGENT_RETRY),
+			it.Equal(evs[4].Epoch, 2),
+			it.Equal(evs[7].Phase, thinker.AGENT_RETURN),
+			it.String(evs[8].Value).Contain("input"),
+		)
+	})
+
+	t.Run("Tokens", func(t *testing.T) {
+		automata := agent.NewAutomata(&StreamMock{}, memory.NewVoid(""), codec.String, codec.String, reasoner.NewVoid[string]())
+
+		ks, evs, err := kinds(automata.Stream(context.Background(), "hello streaming world"), 0)
+		it.Then(t).Must(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Seq(ks).Equal(
+				agent.EVENT_EPOCH,
+				agent.EVENT_TOKEN, agent.EVENT_TOKEN, agent.EVENT_TOKEN,
+				agent.EVENT_REPLY, agent.EVENT_DECODE, agent.EVENT_DECIDE,
+				agent.EVENT_RETURN,
+			),
+			it.Equal(evs[1].Token, "hello"),
+			it.Equal(evs[3].Token, "world."),
+		)
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		automata := agent.NewAutomata(&FlakyStreamMock{failures: 2}, memory.NewVoid(""), codec.String, codec.String, reasoner.NewVoid[string]()).
+			WithRetry(agent.Retry{Attempts: 3, Backoff: time.Millisecond})
+
+		ks, evs, err := kinds(automata.Stream(context.Background(), "hello world"), 0)
+		it.Then(t).Must(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Seq(ks).Equal(
+				agent.EVENT_EPOCH,
+				agent.EVENT_TOKEN, agent.EVENT_RETRY,
+				agent.EVENT_TOKEN, agent.EVENT_RETRY,
+				agent.EVENT_TOKEN, agent.EVENT_TOKEN,
+				agent.EVENT_REPLY, agent.EVENT_DECODE, agent.EVENT_DECIDE,
+				agent.EVENT_RETURN,
+			),
+			it.Equal(evs[1].Token, "partial"),
+			it.Equal(evs[2].Epoch, 1),
+			it.Equal(evs[5].Token, "hello"),
+		)
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		j := &journal{}
+		automata := agent.NewAutomata(&StreamMock{}, memory.NewVoid(""), codec.String, codec.String, &probe{journal: j})
+
+		ks, _, err := kinds(automata.Stream(context.Background(), "hello streaming world"), 2)
+		it.Then(t).Must(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Seq(ks).Equal(agent.EVENT_EPOCH, agent.EVENT_TOKEN),
+			it.Seq(j.seq).Equal("fork", "purge", "start", "abort"),
+		)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		automata := agent.NewAutomata(&ErrorMock{}, memory.NewVoid(""), codec.String, codec.String, reasoner.NewVoid[string]())
+
+		ks, _, err := kinds(automata.Stream(context.Background(), "input"), 0)
+		it.Then(t).ShouldNot(it.Nil(err))
+		it.Then(t).Should(
+			it.Seq(ks).Equal(agent.EVENT_EPOCH),
+		)
+	})
+
+	t.Run("Manifold", func(t *testing.T) {
+		manifold := agent.NewManifold(&InvokeThenReturnMock{}, codec.String, codec.String, &LoopRegistry{})
+
+		ks, evs, err := kinds(manifold.Stream(context.Background(), "input"), 0)
+		it.Then(t).Must(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Seq(ks).Equal(
+				agent.EVENT_EPOCH, agent.EVENT_REPLY, agent.EVENT_INVOKE, agent.EVENT_ANSWER,
+				agent.EVENT_EPOCH, agent.EVENT_REPLY, agent.EVENT_DECODE, agent.EVENT_DECIDE,
+				agent.EVENT_RETURN,
+			),
+			it.Equal(evs[3].Phase, thinker.AGENT_ASK),
+			it.Equal(evs[3].Message.String(), "tool result"),
+			it.Equal(evs[7].Phase, thinker.AGENT_RETURN),
+			it.String(evs[8].Value).Contain("tool result"),
+		)
+	})
+
+	t.Run("ManifoldStop", func(t *testing.T) {
+		llm := &InvokeThenReturnMock{}
+		manifold := agent.NewManifold(llm, codec.String, codec.String, &LoopRegistry{})
+
+		ks, _, err := kinds(manifold.Stream(context.Background(), "input"), 3)
+		it.Then(t).Must(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Seq(ks).Equal(agent.EVENT_EPOCH, agent.EVENT_REPLY, agent.EVENT_INVOKE),
+			it.Equal(llm.calls, 1),
+		)
+	})
+}
@@ -0,0 +1,357 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// Thought is the node of the search tree, the candidate continuation of
+// the parent thought.
+type Thought[B any] struct {
+	// Depth of the thought, the first step is at depth 1
+	Depth int
+
+	// Reply from LLM and its decoded value
+	Reply *chatter.Reply
+	Value B
+
+	// Confidence of the decoder and the score given by the evaluator
+	Confidence float64
+	Score      float64
+
+	// Parent thought, nil at the first step
+	Parent *Thought[B]
+}
+
+// Path from the first step to the thought.
+func (t *Thought[B]) Path() []*Thought[B] {
+	path := make([]*Thought[B], 0, t.Depth)
+	for x := t; x != nil; x = x.Parent {
+		path = append(path, x)
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// Evaluator scores the thought towards the goal, the higher is better.
+type Evaluator[B any] interface {
+	Evaluate(ctx context.Context, goal chatter.Message, thought *Thought[B]) (float64, error)
+}
+
+// EvaluatorFrom is helper to build Evaluator interface from pure function.
+func EvaluatorFrom[B any](f func(context.Context, chatter.Message, *Thought[B]) (float64, error)) Evaluator[B] {
+	return fromEvaluator[B](f)
+}
+
+type fromEvaluator[B any] func(context.Context, chatter.Message, *Thought[B]) (float64, error)
+
+func (f fromEvaluator[B]) Evaluate(ctx context.Context, goal chatter.Message, thought *Thought[B]) (float64, error) {
+	return f(ctx, goal, thought)
+}
+
+// Search strategy of the tree of thoughts
+type Search int
+
+const (
+	// Expands the best thoughts of the deepest level, level by level
+	SEARCH_BEAM Search = iota
+	// Expands the best thought of the whole tree
+	SEARCH_BEST_FIRST
+)
+
+// Default prompt to continue the thought.
+const DefaultStep = "Continue with the next step of the solution, building on the previous steps."
+
+// Tree-of-thoughts agent expands several candidate continuations per step,
+// scores them with the evaluator and explores the most promising ones.
+// Thoughts scored below the prune level or rejected by the decoder are dead
+// ends, the search backtracks to thoughts left unexpanded. The search stops
+// at the depth or at the thought scored at the accept level.
+type TreeOfThoughts[A, B any] struct {
+	llm       chatter.Chatter
+	encoder   thinker.Encoder[A]
+	decoder   thinker.Decoder[B]
+	evaluator Evaluator[B]
+	retry     Retry
+	search    Search
+	width     int
+	depth     int
+	beam      int
+	budget    int
+	prune     float64
+	accept    float64
+	step      chatter.Message
+	model     string
+}
+
+// Creates new tree-of-thoughts agent. By default, the beam search expands
+// the best thought into 3 candidates per step up to depth 3. Thoughts are
+// scored with the decoder's confidence.
+func NewTreeOfThoughts[A, B any](
+	llm chatter.Chatter,
+	encoder thinker.Encoder[A],
+	decoder thinker.Decoder[B],
+) *TreeOfThoughts[A, B] {
+	var step chatter.Prompt
+	step.WithTask(DefaultStep)
+
+	return &TreeOfThoughts[A, B]{
+		llm:     llm,
+		encoder: encoder,
+		decoder: decoder,
+		search:  SEARCH_BEAM,
+		width:   3,
+		depth:   3,
+		beam:    1,
+		accept:  math.Inf(1),
+		step:    &step,
+	}
+}
+
+// Configures the evaluator of thoughts, e.g. the judge model.
+func (tot *TreeOfThoughts[A, B]) WithEvaluator(evaluator Evaluator[B]) *TreeOfThoughts[A, B] {
+	tot.evaluator = evaluator
+	return tot
+}
+
+// Configures the search strategy.
+func (tot *TreeOfThoughts[A, B]) WithSearch(search Search) *TreeOfThoughts[A, B] {
+	tot.search = search
+	return tot
+}
+
+// Configures the number of candidates per expansion and the depth of the tree.
+func (tot *TreeOfThoughts[A, B]) WithShape(width, depth int) *TreeOfThoughts[A, B] {
+	tot.width, tot.depth = max(width, 1), max(depth, 1)
+	return tot
+}
+
+// Configures the number of thoughts expanded per level by the beam search.
+func (tot *TreeOfThoughts[A, B]) WithBeam(beam int) *TreeOfThoughts[A, B] {
+	tot.beam = max(beam, 1)
+	return tot
+}
+
+// Configures the maximum number of expansions, zero is unbounded. The search
+// returns the best thought found so far once the budget is spent.
+func (tot *TreeOfThoughts[A, B]) WithBudget(expansions int) *TreeOfThoughts[A, B] {
+	tot.budget = expansions
+	return tot
+}
+
+// Configures the prune level, thoughts scored below are dead ends.
+func (tot *TreeOfThoughts[A, B]) WithPrune(level float64) *TreeOfThoughts[A, B] {
+	tot.prune = level
+	return tot
+}
+
+// Configures the accept level, the thought scored at or above the level
+// completes the search before the depth.
+func (tot *TreeOfThoughts[A, B]) WithAccept(level float64) *TreeOfThoughts[A, B] {
+	tot.accept = level
+	return tot
+}
+
+// Configures the prompt to continue the thought.
+func (tot *TreeOfThoughts[A, B]) WithStep(step chatter.Message) *TreeOfThoughts[A, B] {
+	tot.step = step
+	return tot
+}
+
+// Configures the retry policy of transient LLM errors.
+func (tot *TreeOfThoughts[A, B]) WithRetry(retry Retry) *TreeOfThoughts[A, B] {
+	tot.retry = retry
+	return tot
+}
+
+// Configures the model name of the LLM, the usage of tokens is accounted
+// to this model (see ledger).
+func (tot *TreeOfThoughts[A, B]) WithModel(model string) *TreeOfThoughts[A, B] {
+	tot.model = model
+	return tot
+}
+
+// Prompt agent, it returns the value of the best leaf.
+func (tot *TreeOfThoughts[A, B]) Prompt(ctx context.Context, input A, opt ...chatter.Opt) (B, error) {
+	leaf, err := tot.Search(ctx, input, opt...)
+	if err != nil {
+		var nul B
+		return nul, err
+	}
+
+	return leaf.Value, nil
+}
+
+// Search the tree of thoughts, it returns the best leaf, use Path to obtain
+// the chain of thoughts. The best thought found so far is returned if
+// the expansion budget is spent before reaching the leaf.
+func (tot *TreeOfThoughts[A, B]) Search(ctx context.Context, input A, opt ...chatter.Opt) (*Thought[B], error) {
+	ctx = scope(ctx, "tree-of-thoughts", tot.model)
+
+	goal, err := tot.encoder.Encode(input)
+	if err != nil {
+		return nil, thinker.ErrCodec.With(err)
+	}
+
+	// nil is the root of the tree, the goal itself
+	frontier := []*Thought[B]{nil}
+	expansions := 0
+
+	for len(frontier) > 0 {
+		var batch []*Thought[B]
+		batch, frontier = tot.next(frontier)
+
+		var best *Thought[B]
+		for i, node := range batch {
+			if tot.budget > 0 && expansions >= tot.budget {
+				frontier = append(frontier, batch[i:]...)
+				break
+			}
+			expansions++
+			seq, err := tot.expand(ctx, goal, node, opt...)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, thought := range seq {
+				if thought.Depth < tot.depth && thought.Score < tot.accept {
+					frontier = append(frontier, thought)
+					continue
+				}
+
+				if best == nil || thought.Score > best.Score {
+					best = thought
+				}
+			}
+		}
+
+		if best != nil {
+			return best, nil
+		}
+
+		if tot.budget > 0 && expansions >= tot.budget {
+			return tot.best(frontier)
+		}
+	}
+
+	return nil, thinker.ErrDeadEnd
+}
+
+// the best thought left unexpanded, the search is the dead end if none
+func (tot *TreeOfThoughts[A, B]) best(frontier []*Thought[B]) (*Thought[B], error) {
+	var best *Thought[B]
+	for _, thought := range frontier {
+		if thought != nil && (best == nil || thought.Score > best.Score) {
+			best = thought
+		}
+	}
+
+	if best == nil {
+		return nil, thinker.ErrDeadEnd
+	}
+	return best, nil
+}
+
+// selects thoughts to expand, the rest of the frontier is kept for backtracking
+func (tot *TreeOfThoughts[A, B]) next(frontier []*Thought[B]) ([]*Thought[B], []*Thought[B]) {
+	slices.SortStableFunc(frontier, func(a, b *Thought[B]) int {
+		if tot.search == SEARCH_BEAM && depthOf(a) != depthOf(b) {
+			return depthOf(b) - depthOf(a)
+		}
+		switch {
+		case scoreOf(a) > scoreOf(b):
+			return -1
+		case scoreOf(a) < scoreOf(b):
+			return 1
+		default:
+			return depthOf(b) - depthOf(a)
+		}
+	})
+
+	n := 1
+	if tot.search == SEARCH_BEAM {
+		for n < len(frontier) && n < tot.beam && depthOf(frontier[n]) == depthOf(frontier[0]) {
+			n++
+		}
+	}
+
+	return frontier[:n:n], frontier[n:]
+}
+
+// expands the thought into candidate continuations, dead ends are pruned
+func (tot *TreeOfThoughts[A, B]) expand(ctx context.Context, goal chatter.Message, parent *Thought[B], opt ...chatter.Opt) ([]*Thought[B], error) {
+	seq := []chatter.Message{goal}
+	if parent != nil {
+		for _, t := range parent.Path() {
+			seq = append(seq, t.Reply, tot.step)
+		}
+	}
+
+	thoughts := make([]*Thought[B], 0, tot.width)
+	for range tot.width {
+		reply, err := tot.retry.prompt(ctx, tot.llm, seq, opt...)
+		if err != nil {
+			return nil, thinker.ErrLLM.With(err)
+		}
+
+		confidence, value, err := tot.decoder.Decode(reply)
+		if err != nil {
+			var feedback chatter.Content
+			if ok := errors.As(err, &feedback); !ok {
+				return nil, err
+			}
+			continue
+		}
+
+		thought := &Thought[B]{
+			Depth:      depthOf(parent) + 1,
+			Reply:      reply,
+			Value:      value,
+			Confidence: confidence,
+			Score:      confidence,
+			Parent:     parent,
+		}
+
+		if tot.evaluator != nil {
+			thought.Score, err = tot.evaluator.Evaluate(ctx, goal, thought)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if thought.Score < tot.prune {
+			continue
+		}
+
+		thoughts = append(thoughts, thought)
+	}
+
+	return thoughts, nil
+}
+
+func depthOf[B any](t *Thought[B]) int {
+	if t == nil {
+		return 0
+	}
+	return t.Depth
+}
+
+func scoreOf[B any](t *Thought[B]) float64 {
+	if t == nil {
+		return 0
+	}
+	return t.Score
+}
@@ -0,0 +1,214 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/agent"
+	"github.com/kshard/thinker/codec"
+)
+
+// TreeMock names the candidate by its position in the tree, e.g. "2.1" is
+// the first continuation of the second thought.
+type TreeMock struct {
+	calls    int
+	children map[string]int
+}
+
+func (m *TreeMock) Usage() chatter.Usage { return chatter.Usage{} }
+
+func (m *TreeMock) Prompt(_ context.Context, prompt []chatter.Message, _ ...chatter.Opt) (*chatter.Reply, error) {
+	if m.children == nil {
+		m.children = make(map[string]int)
+	}
+	m.calls++
+
+	parent := ""
+	for _, msg := range prompt {
+		if reply, ok := msg.(*chatter.Reply); ok {
+			parent = reply.String() + "."
+		}
+	}
+	m.children[parent]++
+
+	return &chatter.Reply{
+		Stage:   chatter.LLM_RETURN,
+		Content: []chatter.Content{chatter.Text(fmt.Sprintf("%s%d", parent, m.children[parent]))},
+	}, nil
+}
+
+// scores thoughts from the table, unknown thoughts are scored 0.1
+func scores(table map[string]float64) agent.Evaluator[string] {
+	return agent.EvaluatorFrom(func(ctx context.Context, goal chatter.Message, t *agent.Thought[string]) (float64, error) {
+		if score, has := table[t.Value]; has {
+			return score, nil
+		}
+		return 0.1, nil
+	})
+}
+
+func values(path []*agent.Thought[string]) []string {
+	seq := make([]string, len(path))
+	for i, t := range path {
+		seq[i] = t.Value
+	}
+	return seq
+}
+
+//------------------------------------------------------------------------------
+// Test Tree of Thoughts
+//------------------------------------------------------------------------------
+
+func TestTreeOfThoughts(t *testing.T) {
+	t.Run("Beam", func(t *testing.T) {
+		llm := &TreeMock{}
+		tot := agent.NewTreeOfThoughts(llm, codec.String, codec.String).
+			WithShape(3, 2).
+			WithEvaluator(scores(map[string]float64{"2": 0.9, "2.2": 0.8, "2.1": 0.3}))
+
+		leaf, err := tot.Search(context.Background(), "puzzle")
+		it.Then(t).Must(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Equal(leaf.Value, "2.2"),
+			it.Equal(leaf.Score, 0.8),
+			it.Seq(values(leaf.Path())).Equal("2", "2.2"),
+			it.Equal(llm.calls, 6),
+		)
+	})
+
+	t.Run("Backtrack", func(t *testing.T) {
+		llm := &TreeMock{}
+		tot := agent.NewTreeOfThoughts(llm, codec.String, codec.String).
+			WithShape(3, 2).
+			WithPrune(0.2).
+			WithEvaluator(scores(map[string]float64{"2": 0.9, "1": 0.5, "1.3": 0.7}))
+
+		leaf, err := tot.Search(context.Background(), "puzzle")
+		it.Then(t).Must(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Seq(values(leaf.Path())).Equal("1", "1.3"),
+			it.Equal(llm.calls, 9),
+		)
+	})
+
+	t.Run("BestFirst", func(t *testing.T) {
+		llm := &TreeMock{}
+		tot := agent.NewTreeOfThoughts(llm, codec.String, codec.String).
+			WithSearch(agent.SEARCH_BEST_FIRST).
+			WithShape(3, 3).
+			WithPrune(0.2).
+			WithEvaluator(scores(map[string]float64{"1": 0.6, "2": 0.5, "1.1": 0.3, "2.1": 0.9, "2.1.2": 0.4}))
+
+		reply, err := tot.Prompt(context.Background(), "puzzle")
+		it.Then(t).Must(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Equal(reply, "2.1.2"),
+			it.Equal(llm.calls, 12),
+		)
+	})
+
+	t.Run("Accept", func(t *testing.T) {
+		tot := agent.NewTreeOfThoughts(&TreeMock{}, codec.String, codec.String).
+			WithSearch(agent.SEARCH_BEST_FIRST).
+			WithShape(3, 3).
+			WithPrune(0.2).
+			WithAccept(0.9).
+			WithEvaluator(scores(map[string]float64{"1": 0.6, "2": 0.5, "1.1": 0.3, "2.1": 0.9}))
+
+		leaf, err := tot.Search(context.Background(), "puzzle")
+		it.Then(t).Must(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Seq(values(leaf.Path())).Equal("2", "2.1"),
+		)
+	})
+
+	t.Run("Budget", func(t *testing.T) {
+		llm := &TreeMock{}
+		tot := agent.NewTreeOfThoughts(llm, codec.String, codec.String).
+			WithShape(2, 5).
+			WithBudget(2).
+			WithEvaluator(scores(map[string]float64{"1": 0.6, "2": 0.5, "1.1": 0.4, "1.2": 0.7}))
+
+		leaf, err := tot.Search(context.Background(), "puzzle")
+		it.Then(t).Must(it.Nil(err))
+		it.Then(t).Should(
+			it.Seq(values(leaf.Path())).Equal("1", "1.2"),
+			it.Equal(llm.calls, 4),
+		)
+	})
+
+	t.Run("BudgetBeam", func(t *testing.T) {
+		llm := &TreeMock{}
+		tot := agent.NewTreeOfThoughts(llm, codec.String, codec.String).
+			WithShape(3, 5).
+			WithBeam(3).
+			WithBudget(2).
+			WithEvaluator(scores(map[string]float64{"1": 0.6, "2": 0.5, "3": 0.4, "1.1": 0.8}))
+
+		leaf, err := tot.Search(context.Background(), "puzzle")
+		it.Then(t).Must(it.Nil(err))
+		it.Then(t).Should(
+			it.Equal(leaf.Value, "1.1"),
+			it.Equal(llm.calls, 6),
+		)
+	})
+
+	t.Run("BudgetDeadEnd", func(t *testing.T) {
+		tot := agent.NewTreeOfThoughts(&TreeMock{}, codec.String, codec.String).
+			WithShape(2, 5).
+			WithPrune(0.5).
+			WithBudget(2).
+			WithEvaluator(scores(map[string]float64{"1": 0.6}))
+
+		_, err := tot.Search(context.Background(), "puzzle")
+		it.Then(t).Should(
+			it.True(errors.Is(err, thinker.ErrDeadEnd)),
+		)
+	})
+
+	t.Run("DeadEnd", func(t *testing.T) {
+		decoder := codec.FromDecoder(func(reply *chatter.Reply) (float64, string, error) {
+			return 0, "", feedbackErr("Wrong move.")
+		})
+
+		_, err := agent.NewTreeOfThoughts(&TreeMock{}, codec.String, decoder).
+			Prompt(context.Background(), "puzzle")
+		it.Then(t).Should(
+			it.True(errors.Is(err, thinker.ErrDeadEnd)),
+		)
+	})
+
+	t.Run("DecoderConfidence", func(t *testing.T) {
+		decoder := codec.FromDecoder(func(reply *chatter.Reply) (float64, string, error) {
+			if reply.String() == "1.3" {
+				return 0.9, reply.String(), nil
+			}
+			return 0.5, reply.String(), nil
+		})
+
+		reply, err := agent.NewTreeOfThoughts(&TreeMock{}, codec.String, decoder).
+			WithShape(3, 2).
+			Prompt(context.Background(), "puzzle")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(reply, "1.3"),
+		)
+	})
+}
@@ -0,0 +1,146 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package agent_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/agent"
+	"github.com/kshard/thinker/codec"
+	"github.com/kshard/thinker/ledger"
+	"github.com/kshard/thinker/memory"
+	"github.com/kshard/thinker/reasoner"
+	"github.com/kshard/thinker/tracer"
+)
+
+// ToolMock invokes the tool at the first call, then echoes the input.
+type ToolMock struct{ InvokeThenReturnMock }
+
+func (m *ToolMock) Prompt(ctx context.Context, prompt []chatter.Message, opt ...chatter.Opt) (*chatter.Reply, error) {
+	reply, err := m.InvokeThenReturnMock.Prompt(ctx, prompt, opt...)
+	if err == nil && reply.Stage == chatter.LLM_INVOKE {
+		reply.Content = []chatter.Content{chatter.Invoke{Cmd: "search"}}
+	}
+	return reply, err
+}
+
+// names of spans
+func names(spans []tracer.Span) []string {
+	seq := make([]string, len(spans))
+	for i, s := range spans {
+		seq[i] = s.Name
+	}
+	return seq
+}
+
+//------------------------------------------------------------------------------
+// Test Trace
+//------------------------------------------------------------------------------
+
+func TestTrace(t *testing.T) {
+	t.Run("Automata", func(t *testing.T) {
+		mem := tracer.NewMemory()
+		ctx := tracer.New(mem).Context(context.Background())
+
+		automata := agent.NewAutomata(&Mock{}, memory.NewVoid(""), codec.String, codec.String,
+			reasoner.From(func(state thinker.State[string]) (thinker.Phase, chatter.Message, error) {
+				if state.Epoch < 2 {
+					return thinker.AGENT_RETRY, nil, nil
+				}
+				return thinker.AGENT_RETURN, nil, nil
+			}),
+		)
+
+		_, err := automata.Prompt(ctx, "input")
+		it.Then(t).Must(it.Nil(err))
+
+		spans := mem.Spans()
+		it.Then(t).Must(
+			it.Seq(names(spans)).Equal(
+				"llm.prompt", "agent.epoch",
+				"llm.prompt", "agent.epoch",
+				"agent.automata",
+			),
+		)
+
+		run := spans[4]
+		it.Then(t).Should(
+			it.Equal(spans[0].Parent, spans[1].ID),
+			it.Equal(spans[1].Parent, run.ID),
+			it.Equal(spans[3].Parent, run.ID),
+			it.Equal(spans[0].Attrs["stage"], any("return")),
+			it.Equal(spans[1].Attrs["phase"], any("retry")),
+			it.Equal(spans[3].Attrs["phase"], any("return")),
+			it.Equal(spans[3].Attrs["epoch"], any(2)),
+		)
+	})
+
+	t.Run("Model", func(t *testing.T) {
+		mem := tracer.NewMemory()
+		ctx := ledger.WithModel(tracer.New(mem).Context(context.Background()), "echo")
+
+		automata := agent.NewAutomata(&Mock{}, memory.NewVoid(""), codec.String, codec.String, reasoner.NewVoid[string]())
+		_, err := automata.Prompt(ctx, "input")
+		it.Then(t).Must(it.Nil(err))
+
+		spans := mem.Lookup("llm.prompt")
+		it.Then(t).Must(it.Equal(len(spans), 1))
+		it.Then(t).Should(
+			it.Equal(spans[0].Attrs["model"], any("echo")),
+		)
+	})
+
+	t.Run("AutomataError", func(t *testing.T) {
+		mem := tracer.NewMemory()
+		ctx := tracer.New(mem).Context(context.Background())
+
+		automata := agent.NewAutomata(&ErrorMock{}, memory.NewVoid(""), codec.String, codec.String, reasoner.NewVoid[string]())
+		_, err := automata.Prompt(ctx, "input")
+		it.Then(t).ShouldNot(it.Nil(err))
+
+		spans := mem.Spans()
+		it.Then(t).Must(
+			it.Seq(names(spans)).Equal("llm.prompt", "agent.epoch", "agent.automata"),
+		)
+		it.Then(t).ShouldNot(
+			it.Equal(spans[0].Error, ""),
+			it.Equal(spans[1].Error, ""),
+			it.Equal(spans[2].Error, ""),
+		)
+	})
+
+	t.Run("Manifold", func(t *testing.T) {
+		mem := tracer.NewMemory()
+		ctx := tracer.New(mem).Context(context.Background())
+
+		manifold := agent.NewManifold(&ToolMock{}, codec.String, codec.String, &LoopRegistry{})
+		_, err := manifold.Prompt(ctx, "input")
+		it.Then(t).Must(it.Nil(err))
+
+		spans := mem.Spans()
+		it.Then(t).Must(
+			it.Seq(names(spans)).Equal(
+				"llm.prompt", "tool.invoke", "agent.epoch",
+				"llm.prompt", "agent.epoch",
+				"agent.manifold",
+			),
+		)
+
+		it.Then(t).Should(
+			it.Equal(spans[1].Parent, spans[2].ID),
+			it.Equal(spans[1].Attrs["tool"], any("search")),
+			it.Equal(spans[1].Attrs["phase"], any("ask")),
+			it.Equal(spans[2].Parent, spans[5].ID),
+		)
+	})
+}
@@ -0,0 +1,22 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package thinker
+
+import (
+	"context"
+
+	"github.com/kshard/chatter"
+)
+
+// Human in the loop, used by agent to escalate the question (e.g. approval or
+// clarification). The answer resumes the agent as the next prompt, the error
+// rejects the escalation and aborts the agent.
+type Approver interface {
+	Approve(context.Context, chatter.Message) (chatter.Message, error)
+}
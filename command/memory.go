@@ -0,0 +1,233 @@
+//
+// Copyright (C) 2025 - 2026 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package command
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Store is the long-term memory of notes managed by LLM through memory tools.
+type Store interface {
+	// Remember the note under the key, replacing the existing one.
+	Remember(key, text string) error
+
+	// Recall the note by the key.
+	Recall(key string) (string, bool, error)
+
+	// Forget the note.
+	Forget(key string) error
+
+	// List keys of notes.
+	List() ([]string, error)
+}
+
+// Native toolset exposing the store to LLM as remember, recall, forget
+// and list tools.
+//
+//	command.NewRegistry().WithNative("memory", command.MemoryTools(store)...)
+func MemoryTools(store Store) []Native {
+	return []Native{
+		From(
+			&mcp.Tool{Name: "remember", Description: "remember the note under the key for later use, the existing note is replaced"},
+			func(ctx context.Context, req *mcp.CallToolRequest, in noteInput) (*mcp.CallToolResult, noteReply, error) {
+				if err := store.Remember(in.Key, in.Text); err != nil {
+					return nil, noteReply{}, err
+				}
+				return nil, noteReply{Key: in.Key, Found: true}, nil
+			},
+		),
+		From(
+			&mcp.Tool{Name: "recall", Description: "recall the note previously remembered under the key"},
+			func(ctx context.Context, req *mcp.CallToolRequest, in keyInput) (*mcp.CallToolResult, noteReply, error) {
+				text, has, err := store.Recall(in.Key)
+				if err != nil {
+					return nil, noteReply{}, err
+				}
+				return nil, noteReply{Key: in.Key, Text: text, Found: has}, nil
+			},
+		),
+		From(
+			&mcp.Tool{Name: "forget", Description: "forget the note remembered under the key"},
+			func(ctx context.Context, req *mcp.CallToolRequest, in keyInput) (*mcp.CallToolResult, noteReply, error) {
+				if err := store.Forget(in.Key); err != nil {
+					return nil, noteReply{}, err
+				}
+				return nil, noteReply{Key: in.Key}, nil
+			},
+		),
+		From(
+			&mcp.Tool{Name: "list", Description: "list keys of remembered notes"},
+			func(ctx context.Context, req *mcp.CallToolRequest, in listInput) (*mcp.CallToolResult, listReply, error) {
+				keys, err := store.List()
+				if err != nil {
+					return nil, listReply{}, err
+				}
+				return nil, listReply{Keys: keys}, nil
+			},
+		),
+	}
+}
+
+type noteInput struct {
+	Key  string `json:"key" jsonschema:"unique key of the note"`
+	Text string `json:"text" jsonschema:"text of the note"`
+}
+
+type keyInput struct {
+	Key string `json:"key" jsonschema:"unique key of the note"`
+}
+
+type listInput struct{}
+
+type noteReply struct {
+	Key   string `json:"key" jsonschema:"unique key of the note"`
+	Text  string `json:"text,omitempty" jsonschema:"text of the note"`
+	Found bool   `json:"found" jsonschema:"true if the note is known"`
+}
+
+type listReply struct {
+	Keys []string `json:"keys" jsonschema:"keys of remembered notes"`
+}
+
+//------------------------------------------------------------------------------
+
+// Scratchpad is the in-memory key-value store of notes, it persists across
+// steps of agent but not across runs of the application.
+type Scratchpad struct {
+	mu    sync.Mutex
+	keys  []string
+	notes map[string]string
+}
+
+var _ Store = (*Scratchpad)(nil)
+
+// Creates new scratchpad.
+func NewScratchpad() *Scratchpad {
+	return &Scratchpad{
+		keys:  make([]string, 0),
+		notes: make(map[string]string),
+	}
+}
+
+func (s *Scratchpad) Remember(key, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, has := s.notes[key]; !has {
+		s.keys = append(s.keys, key)
+	}
+	s.notes[key] = text
+	return nil
+}
+
+func (s *Scratchpad) Recall(key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	text, has := s.notes[key]
+	return text, has, nil
+}
+
+func (s *Scratchpad) Forget(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.notes, key)
+	s.keys = slices.DeleteFunc(s.keys, func(x string) bool { return x == key })
+	return nil
+}
+
+func (s *Scratchpad) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.keys), nil
+}
+
+//------------------------------------------------------------------------------
+
+// InspectableMemory is the memory that allows inspection of observations.
+type InspectableMemory interface {
+	thinker.Memory
+	thinker.Inspector
+}
+
+// Adapts the memory as the store of notes. Each note is the observation,
+// the key is the query and the note text is the reply. Notes survive runs
+// of the application if memory is durable (e.g. memory.NewDurable).
+func FromMemory(memory InspectableMemory) Store {
+	return &memoryStore{memory: memory}
+}
+
+type memoryStore struct {
+	mu     sync.Mutex
+	memory InspectableMemory
+}
+
+func (s *memoryStore) Remember(key, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.forget(key)
+	s.memory.Commit(thinker.NewObservation(chatter.Text(key), chatter.Text(text)))
+	return nil
+}
+
+func (s *memoryStore) Recall(key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	text, has := "", false
+	for e := range s.memory.Observations() {
+		if isNote(e, key) {
+			text, has = e.Reply.Content.String(), true
+		}
+	}
+	return text, has, nil
+}
+
+func (s *memoryStore) Forget(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.forget(key)
+	return nil
+}
+
+func (s *memoryStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0)
+	for e := range s.memory.Observations() {
+		if key, ok := e.Query.Content.(chatter.Text); ok && !slices.Contains(keys, string(key)) {
+			keys = append(keys, string(key))
+		}
+	}
+	return keys, nil
+}
+
+func (s *memoryStore) forget(key string) {
+	for e := range s.memory.Observations() {
+		if isNote(e, key) {
+			s.memory.Forget(e.Created)
+		}
+	}
+}
+
+func isNote(e *thinker.Observation, key string) bool {
+	k, ok := e.Query.Content.(chatter.Text)
+	return ok && string(k) == key
+}
@@ -0,0 +1,73 @@
+//
+// Copyright (C) 2025 - 2026 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package command_test
+
+import (
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/command"
+	"github.com/kshard/thinker/memory"
+)
+
+func TestMemoryTools(t *testing.T) {
+	invoke := func(r *command.Registry, name string, args map[string]any) string {
+		reply := replyOne(name, args)
+		phase, msg, err := r.Invoke(&reply)
+		it.Then(t).Must(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_ASK),
+		)
+		return string(msg.(*chatter.Answer).Yield[0].Value)
+	}
+
+	for name, store := range map[string]command.Store{
+		"Scratchpad": command.NewScratchpad(),
+		"Memory":     command.FromMemory(memory.NewStream(memory.INFINITE, "")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := command.NewRegistry().WithNative("memory", command.MemoryTools(store)...)
+
+			names := make([]string, 0)
+			for _, cmd := range r.Context() {
+				names = append(names, cmd.Cmd)
+			}
+			it.Then(t).Should(
+				it.Seq(names).Contain().AllOf("memory_remember", "memory_recall", "memory_forget", "memory_list"),
+			)
+
+			invoke(r, "memory_remember", map[string]any{"key": "a", "text": "apple"})
+			invoke(r, "memory_remember", map[string]any{"key": "b", "text": "banana"})
+			invoke(r, "memory_remember", map[string]any{"key": "a", "text": "avocado"})
+
+			it.Then(t).Should(
+				it.String(invoke(r, "memory_recall", map[string]any{"key": "a"})).Contain("avocado"),
+				it.String(invoke(r, "memory_list", map[string]any{})).Contain(`\"a\"`),
+				it.String(invoke(r, "memory_list", map[string]any{})).Contain(`\"b\"`),
+			)
+
+			invoke(r, "memory_forget", map[string]any{"key": "a"})
+			it.Then(t).Should(
+				it.String(invoke(r, "memory_recall", map[string]any{"key": "a"})).Contain(`\"found\":false`),
+			)
+
+			a, _, _ := store.Recall("a")
+			b, has, _ := store.Recall("b")
+			keys, _ := store.List()
+			it.Then(t).Should(
+				it.Equal(a, ""),
+				it.Equal(b, "banana"),
+				it.True(has),
+				it.Seq(keys).Equal("b"),
+			)
+		})
+	}
+}
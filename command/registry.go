@@ -15,8 +15,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os/exec"
 	"strings"
+	"sync"
 
 	"github.com/kshard/chatter"
 	"github.com/kshard/thinker"
@@ -33,8 +35,10 @@ type Server interface {
 const kSchemaSplit = "_"
 
 type Registry struct {
+	mu      sync.Mutex
 	servers map[string]Server
 	cmds    chatter.Registry
+	version int
 }
 
 var _ thinker.Registry = (*Registry)(nil)
@@ -100,25 +104,35 @@ func (r *Registry) Attach(id string, server Server) error {
 		return fmt.Errorf("server ID cannot be empty")
 	}
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.servers[id] = server
 	r.cmds = chatter.Registry{}
+	r.version++
 
 	return nil
 }
 
 // Context returns the registry as LLM embeddable schema.
 // It fetches the list of available tools from all attached MCP servers.
+// Servers are listed outside of the lock, the list is cached unless
+// the server is attached meanwhile.
 func (r *Registry) Context() chatter.Registry {
+	r.mu.Lock()
 	// Return cached if available
-	if len(r.cmds) > 0 {
-		return r.cmds
+	if cmds := r.cmds; len(cmds) > 0 {
+		r.mu.Unlock()
+		return cmds
 	}
+	servers, version := maps.Clone(r.servers), r.version
+	r.mu.Unlock()
 
 	ctx := context.Background()
 	seq := make([]chatter.Cmd, 0)
 
 	// Collect tools from all attached servers
-	for id, srv := range r.servers {
+	for id, srv := range servers {
 		tools, err := srv.ListTools(ctx, &mcp.ListToolsParams{})
 		if err != nil {
 			continue
@@ -130,8 +144,22 @@ func (r *Registry) Context() chatter.Registry {
 		}
 	}
 
-	r.cmds = seq
-	return r.cmds
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.version == version {
+		r.cmds = seq
+	}
+	return seq
+}
+
+// returns the server attached with the id
+func (r *Registry) server(id string) (Server, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	srv, has := r.servers[id]
+	return srv, has
 }
 
 // Invoke executes the tools requested by the LLM via the appropriate MCP server.
@@ -147,7 +175,7 @@ func (r *Registry) Invoke(reply *chatter.Reply) (thinker.Phase, chatter.Message,
 		id, tool := seq[0], seq[1]
 
 		// Find which server handles this tool
-		srv, exists := r.servers[id]
+		srv, exists := r.server(id)
 		if !exists {
 			return pack(
 				fmt.Appendf(nil, "tool %s is not available in any attached MCP server", name),
@@ -13,6 +13,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/fogfish/it/v2"
 	"github.com/kshard/chatter"
@@ -164,6 +165,31 @@ func TestRegistryContext(t *testing.T) {
 			it.Seq(seq).Contain("fs_read.0", "db_query.0"),
 		)
 	})
+
+	t.Run("ContextOutsideLock", func(t *testing.T) {
+		registry := command.NewRegistry()
+		slow := &slowMock{mock: mockSeq(1, "read", "Read file"), listing: make(chan struct{}, 1), release: make(chan struct{})}
+		registry.Attach("fs", slow)
+
+		done := make(chan chatter.Registry)
+		go func() { done <- registry.Context() }()
+		<-slow.listing
+
+		attached := make(chan error)
+		go func() { attached <- registry.Attach("db", mockSeq(1, "query", "Query database")) }()
+		select {
+		case err := <-attached:
+			it.Then(t).Must(it.Nil(err))
+		case <-time.After(time.Second):
+			t.Fatal("attach is blocked by listing of tools")
+		}
+
+		close(slow.release)
+		it.Then(t).Should(
+			it.Equal(len(<-done), 1),
+			it.Equal(len(registry.Context()), 2),
+		)
+	})
 }
 
 func TestRegistryInvoke(t *testing.T) {
@@ -310,6 +336,22 @@ func (m *mock) CallTool(ctx context.Context, params *mcp.CallToolParams) (*mcp.C
 
 func (m *mock) Close() error { return nil }
 
+// Mock MCP session, which lists tools once released
+type slowMock struct {
+	*mock
+	listing chan struct{}
+	release chan struct{}
+}
+
+func (m *slowMock) ListTools(ctx context.Context, params *mcp.ListToolsParams) (*mcp.ListToolsResult, error) {
+	select {
+	case m.listing <- struct{}{}:
+	default:
+	}
+	<-m.release
+	return m.mock.ListTools(ctx, params)
+}
+
 // Helper to create a reply with tool calls
 func replyOne(name string, args map[string]any) chatter.Reply {
 	content := make([]chatter.Content, 1)
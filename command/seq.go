@@ -12,7 +12,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/kshard/chatter"
 	"github.com/kshard/thinker"
@@ -20,8 +22,10 @@ import (
 )
 
 type SeqRegistry struct {
-	regs []*Registry
-	cmds chatter.Registry
+	mu      sync.Mutex
+	regs    []*Registry
+	cmds    chatter.Registry
+	version int
 }
 
 var _ thinker.Registry = (*SeqRegistry)(nil)
@@ -38,21 +42,45 @@ func (r *SeqRegistry) Bind(reg *Registry) {
 		return
 	}
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.regs = append(r.regs, reg)
+	r.cmds = chatter.Registry{}
+	r.version++
 }
 
+// Context returns tools of all bound registries. Registries are listed
+// outside of the lock, the list is cached unless the registry is bound
+// meanwhile.
 func (r *SeqRegistry) Context() chatter.Registry {
-	if len(r.cmds) > 0 {
-		return r.cmds
+	r.mu.Lock()
+	if cmds := r.cmds; len(cmds) > 0 {
+		r.mu.Unlock()
+		return cmds
 	}
+	regs, version := slices.Clone(r.regs), r.version
+	r.mu.Unlock()
 
 	seq := make([]chatter.Cmd, 0)
-	for _, reg := range r.regs {
+	for _, reg := range regs {
 		seq = append(seq, reg.Context()...)
 	}
 
-	r.cmds = seq
-	return r.cmds
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.version == version {
+		r.cmds = seq
+	}
+	return seq
+}
+
+func (r *SeqRegistry) registries() []*Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.regs)
 }
 
 func (r *SeqRegistry) Invoke(reply *chatter.Reply) (phase thinker.Phase, msg chatter.Message, err error) {
@@ -68,8 +96,8 @@ func (r *SeqRegistry) Invoke(reply *chatter.Reply) (phase thinker.Phase, msg cha
 
 		var srv Server
 		var exists bool
-		for _, reg := range r.regs {
-			srv, exists = reg.servers[id]
+		for _, reg := range r.registries() {
+			srv, exists = reg.server(id)
 			if exists {
 				break
 			}
@@ -8,7 +8,12 @@
 
 package thinker
 
-import "github.com/fogfish/faults"
+import (
+	"fmt"
+	"time"
+
+	"github.com/fogfish/faults"
+)
 
 // Common agents errors
 const (
@@ -17,7 +22,36 @@ const (
 	ErrUnknown     = faults.Type("unkown agent statet")
 	ErrAborted     = faults.Type("execution aborted")
 	ErrMaxEpoch    = faults.Safe1[int]("max epoch %d is reached")
+	ErrMaxTime     = faults.Safe1[time.Duration]("max time %s is reached")
+	ErrMaxTokens   = faults.Safe1[int]("max tokens %d is reached")
+	ErrMaxCost     = faults.Safe1[float64]("max cost %.4f is reached")
+	ErrNoState     = faults.Safe1[string]("unknown state %s")
+	ErrNoTransit   = faults.Safe1[string]("no transition from state %s")
+	ErrNoApprover  = faults.Type("escalation requires approver")
+	ErrDeadEnd     = faults.Type("all thoughts are dead ends")
+	ErrMaxSteps    = faults.Type("max steps is reached")
+	ErrToolLoop    = faults.Type("tool call is repeated")
 	ErrCmd         = faults.Type("command I/O has failed")
 	ErrCmdConflict = faults.Type("command already exists")
 	ErrCmdInvalid  = faults.Type("invalid command specification, missing required attributes")
 )
+
+// StepsError is returned if the tool-use loop is aborted, either the budget
+// of steps is exhausted (ErrMaxSteps) or the model repeats the same tool call
+// (ErrToolLoop). It reports the number of steps and the last tool called.
+type StepsError struct {
+	// Number of steps made by the loop
+	Steps int
+
+	// The last tool called, tools called in parallel are separated by comma
+	Tool string
+
+	// The reason, ErrMaxSteps or ErrToolLoop
+	Err error
+}
+
+func (e *StepsError) Error() string {
+	return fmt.Sprintf("%s at step %d, the last tool %q: %s", e.Err, e.Steps, e.Tool, ErrAborted)
+}
+
+func (e *StepsError) Unwrap() []error { return []error{e.Err, ErrAborted} }
@@ -0,0 +1,170 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package ledger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kshard/chatter"
+)
+
+// Scope of the usage: the model, the agent (e.g. the prompt file) and
+// the step of the pipeline.
+type Scope struct {
+	Model string `json:"model,omitempty"`
+	Agent string `json:"agent,omitempty"`
+	Step  string `json:"step,omitempty"`
+}
+
+// Price of the model in currency units per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// Usage of tokens and its cost in currency units.
+type Usage struct {
+	InputTokens int     `json:"inputTokens"`
+	ReplyTokens int     `json:"replyTokens"`
+	Cost        float64 `json:"cost"`
+}
+
+// Total number of tokens.
+func (u Usage) Tokens() int { return u.InputTokens + u.ReplyTokens }
+
+func (u Usage) add(x Usage) Usage {
+	return Usage{
+		InputTokens: u.InputTokens + x.InputTokens,
+		ReplyTokens: u.ReplyTokens + x.ReplyTokens,
+		Cost:        u.Cost + x.Cost,
+	}
+}
+
+// Ledger accounts the token usage of LLM replies by the scope, turning usage
+// into cost using the price of the model. The ledger is carried in the context,
+// agents running within the context account every reply.
+type Ledger struct {
+	mu     sync.Mutex
+	prices map[string]Price
+	order  []Scope
+	usage  map[Scope]Usage
+}
+
+// Creates new ledger.
+func New() *Ledger {
+	return &Ledger{
+		prices: make(map[string]Price),
+		usage:  make(map[Scope]Usage),
+	}
+}
+
+// Configures the price of the model.
+func (l *Ledger) WithPrice(model string, price Price) *Ledger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prices[model] = price
+	return l
+}
+
+// Binds the ledger to the context.
+func (l *Ledger) Context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ledgerKey{}, l)
+}
+
+// Accounts the usage within the scope.
+func (l *Ledger) Account(scope Scope, usage chatter.Usage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	price := l.prices[scope.Model]
+	u, has := l.usage[scope]
+	if !has {
+		l.order = append(l.order, scope)
+	}
+	l.usage[scope] = u.add(Usage{
+		InputTokens: usage.InputTokens,
+		ReplyTokens: usage.ReplyTokens,
+		Cost:        (float64(usage.InputTokens)*price.Input + float64(usage.ReplyTokens)*price.Output) / 1e6,
+	})
+}
+
+// Returns the report of accounted usage.
+func (l *Ledger) Report() Report {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	report := Report{Lines: make([]Line, len(l.order))}
+	for i, scope := range l.order {
+		usage := l.usage[scope]
+		report.Lines[i] = Line{Scope: scope, Usage: usage}
+		report.Total = report.Total.add(usage)
+	}
+	return report
+}
+
+// Forgets accounted usage, prices are retained.
+func (l *Ledger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.order = nil
+	l.usage = make(map[Scope]Usage)
+}
+
+//------------------------------------------------------------------------------
+
+type ledgerKey struct{}
+type scopeKey struct{}
+
+// Accounts the usage to the ledger bound to the context, within the scope of
+// the context. It does nothing if the context has no ledger.
+func Account(ctx context.Context, usage chatter.Usage) {
+	if l, ok := ctx.Value(ledgerKey{}).(*Ledger); ok && l != nil {
+		l.Account(ScopeOf(ctx), usage)
+	}
+}
+
+// Returns the ledger bound to the context, nil if the context has no ledger.
+func Of(ctx context.Context) *Ledger {
+	l, _ := ctx.Value(ledgerKey{}).(*Ledger)
+	return l
+}
+
+// Returns the scope of the context.
+func ScopeOf(ctx context.Context) Scope {
+	scope, _ := ctx.Value(scopeKey{}).(Scope)
+	return scope
+}
+
+// Scopes the context to the model.
+func WithModel(ctx context.Context, model string) context.Context {
+	scope := ScopeOf(ctx)
+	scope.Model = model
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// Scopes the context to the agent, e.g. the name of the prompt file.
+func WithAgent(ctx context.Context, agent string) context.Context {
+	scope := ScopeOf(ctx)
+	scope.Agent = agent
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// Scopes the context to the step of the pipeline. Steps are nested, the step
+// within the step is scoped by the path (e.g. "step 2/task 3").
+func WithStep(ctx context.Context, step string) context.Context {
+	scope := ScopeOf(ctx)
+	if scope.Step != "" {
+		step = scope.Step + "/" + step
+	}
+	scope.Step = step
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
@@ -0,0 +1,87 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package ledger_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker/ledger"
+)
+
+func TestLedger(t *testing.T) {
+	t.Run("Void", func(t *testing.T) {
+		ledger.Account(context.Background(), chatter.Usage{InputTokens: 10})
+	})
+
+	t.Run("Scope", func(t *testing.T) {
+		ctx := ledger.WithModel(context.Background(), "m")
+		ctx = ledger.WithAgent(ctx, "a.prompt")
+		ctx = ledger.WithStep(ctx, "step 2")
+		ctx = ledger.WithStep(ctx, "task 3")
+
+		it.Then(t).Should(
+			it.Equal(ledger.ScopeOf(ctx), ledger.Scope{Model: "m", Agent: "a.prompt", Step: "step 2/task 3"}),
+		)
+	})
+
+	t.Run("Cost", func(t *testing.T) {
+		l := ledger.New().
+			WithPrice("small", ledger.Price{Input: 1, Output: 2}).
+			WithPrice("large", ledger.Price{Input: 10, Output: 20})
+
+		small := ledger.WithModel(l.Context(context.Background()), "small")
+		large := ledger.WithModel(l.Context(context.Background()), "large")
+
+		ledger.Account(small, chatter.Usage{InputTokens: 1_000_000, ReplyTokens: 1_000_000})
+		ledger.Account(large, chatter.Usage{InputTokens: 100_000, ReplyTokens: 100_000})
+		ledger.Account(small, chatter.Usage{InputTokens: 1_000_000})
+
+		report := l.Report()
+		it.Then(t).Should(
+			it.Equal(len(report.Lines), 2),
+			it.Equal(report.Total.Tokens(), 3_200_000),
+			it.Equal(report.Total.Cost, 7.0),
+			it.Equal(report.Lines[0].Model, "small"),
+			it.Equal(report.Lines[0].Cost, 4.0),
+			it.Equal(report.Lines[1].Cost, 3.0),
+		)
+
+		l.Reset()
+		it.Then(t).Should(
+			it.Equal(l.Report().Total.Tokens(), 0),
+		)
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		l := ledger.New()
+		ctx := ledger.WithAgent(l.Context(context.Background()), "think.prompt")
+		ledger.Account(ledger.WithStep(ctx, "step 1"), chatter.Usage{InputTokens: 100})
+
+		ctx = ledger.WithAgent(ctx, "react.prompt")
+		step := ledger.WithStep(ctx, "step 2")
+		ledger.Account(ledger.WithStep(step, "task 1"), chatter.Usage{InputTokens: 60})
+		ledger.Account(ledger.WithStep(step, "task 2"), chatter.Usage{InputTokens: 840})
+
+		report := l.Report()
+		steps := report.ByStep()
+		agents := report.ByAgent()
+		it.Then(t).Should(
+			it.Equal(steps[0].Key, "step 2/task 2"),
+			it.Equal(steps[0].Ratio, 0.84),
+			it.Equal(steps[2].Key, "step 2/task 1"),
+			it.Equal(agents[0].Key, "react.prompt"),
+			it.Equal(agents[0].Tokens(), 900),
+			it.String(report.String()).Contain("total: 1000 tokens"),
+			it.String(report.String()).Contain("step 2/task 2: 840 tokens (84%)"),
+		)
+	})
+}
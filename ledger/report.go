@@ -0,0 +1,92 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package ledger
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Line of the report, the usage within the scope.
+type Line struct {
+	Scope
+	Usage
+}
+
+// Report of the ledger, lines are in the order of the first usage.
+type Report struct {
+	Total Usage
+	Lines []Line
+}
+
+// Share of the usage, the ratio of tokens to the total.
+type Share struct {
+	Key string
+	Usage
+	Ratio float64
+}
+
+// Shares of the usage, ordered by tokens.
+type Shares []Share
+
+func (s Shares) String() string {
+	sb := strings.Builder{}
+	for _, x := range s {
+		key := x.Key
+		if key == "" {
+			key = "(none)"
+		}
+		fmt.Fprintf(&sb, "%s: %d tokens (%.0f%%), cost %.4f\n", key, x.Tokens(), 100*x.Ratio, x.Cost)
+	}
+	return sb.String()
+}
+
+// Breaks the usage down by the key of the scope.
+func (r Report) By(key func(Scope) string) Shares {
+	index := map[string]int{}
+	shares := Shares{}
+	for _, line := range r.Lines {
+		k := key(line.Scope)
+		i, has := index[k]
+		if !has {
+			i = len(shares)
+			index[k] = i
+			shares = append(shares, Share{Key: k})
+		}
+		shares[i].Usage = shares[i].add(line.Usage)
+	}
+
+	for i := range shares {
+		if total := r.Total.Tokens(); total > 0 {
+			shares[i].Ratio = float64(shares[i].Tokens()) / float64(total)
+		}
+	}
+
+	slices.SortStableFunc(shares, func(a, b Share) int { return b.Tokens() - a.Tokens() })
+	return shares
+}
+
+// Breaks the usage down by the model.
+func (r Report) ByModel() Shares { return r.By(func(s Scope) string { return s.Model }) }
+
+// Breaks the usage down by the agent.
+func (r Report) ByAgent() Shares { return r.By(func(s Scope) string { return s.Agent }) }
+
+// Breaks the usage down by the step of the pipeline.
+func (r Report) ByStep() Shares { return r.By(func(s Scope) string { return s.Step }) }
+
+// The total usage and its breakdown by steps of the pipeline.
+//
+//	total: 10000 tokens, cost 0.1200
+//	step 2/task 3: 8400 tokens (84%), cost 0.1000
+//	step 1: 1600 tokens (16%), cost 0.0200
+func (r Report) String() string {
+	return fmt.Sprintf("total: %d tokens, cost %.4f\n", r.Total.Tokens(), r.Total.Cost) + r.ByStep().String()
+}
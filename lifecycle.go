@@ -0,0 +1,47 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package thinker
+
+import "context"
+
+// Lifecycle is the optional interface of reasoners, memories and registries.
+// Agents notify components about the run of every prompt: the start of the
+// run, the end of the run with results and the abort of the run with error.
+type Lifecycle interface {
+	OnStart(context.Context)
+	OnEnd(context.Context)
+	OnAbort(context.Context, error)
+}
+
+// Notifies components about the start of the run.
+func OnStart(ctx context.Context, components ...any) {
+	for _, c := range components {
+		if h, ok := c.(Lifecycle); ok {
+			h.OnStart(ctx)
+		}
+	}
+}
+
+// Notifies components about the end of the run.
+func OnEnd(ctx context.Context, components ...any) {
+	for _, c := range components {
+		if h, ok := c.(Lifecycle); ok {
+			h.OnEnd(ctx)
+		}
+	}
+}
+
+// Notifies components about the abort of the run.
+func OnAbort(ctx context.Context, err error, components ...any) {
+	for _, c := range components {
+		if h, ok := c.(Lifecycle); ok {
+			h.OnAbort(ctx, err)
+		}
+	}
+}
@@ -9,6 +9,10 @@
 package thinker
 
 import (
+	"context"
+	"iter"
+	"time"
+
 	"github.com/fogfish/guid/v2"
 	"github.com/kshard/chatter"
 	"github.com/kshard/float8"
@@ -30,6 +34,47 @@ type Memory interface {
 	Context(chatter.Message) []chatter.Message
 }
 
+// SessionMemory is the memory scoped to the execution context (e.g. the user
+// session). Agents bind the memory to the context at the beginning of
+// every prompt, using the bound memory for the rest of execution.
+type SessionMemory interface {
+	Memory
+
+	// Resolves the memory bound to the context.
+	Session(context.Context) Memory
+}
+
+// Inspector is the optional interface of memory, exposing retained
+// observations for debugging, auditing and deletion requests.
+type Inspector interface {
+	// Iterates retained observations in the order of commits.
+	Observations() iter.Seq[*Observation]
+
+	// Lookup the retained observation by its id.
+	Lookup(guid.K) (*Observation, bool)
+
+	// Iterates retained observations created within the time range [from, to).
+	Range(from, to time.Time) iter.Seq[*Observation]
+
+	// Forget retained observations. The tool invocation and its result are
+	// forgotten together.
+	Forget(...guid.K)
+
+	// Number of retained observations.
+	Len() int
+
+	// Estimated number of tokens used by retained observations.
+	Tokens() int
+}
+
+// Binds the memory to the context if the memory is session aware.
+func MemoryOf(ctx context.Context, memory Memory) Memory {
+	if s, ok := memory.(SessionMemory); ok {
+		return s.Session(ctx)
+	}
+	return memory
+}
+
 // The observation made by agent, it contains LLMs prompt, reply, environment
 // status and other metadata.
 type Observation struct {
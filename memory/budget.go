@@ -0,0 +1,158 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// Estimator approximates the number of tokens consumed by the message.
+type Estimator func(chatter.Message) int
+
+// Approx estimates tokens using the rule of thumb: 4 characters per token.
+// Unlike `String()`, it accounts tool invocations and tool answers.
+func Approx(msg chatter.Message) int {
+	n := 0
+	switch v := msg.(type) {
+	case *chatter.Reply:
+		for _, c := range v.Content {
+			switch x := c.(type) {
+			case chatter.Invoke:
+				n += len(x.Cmd) + len(x.Args.Value)
+			default:
+				n += len(c.String())
+			}
+		}
+	case *chatter.Answer:
+		for _, x := range v.Yield {
+			n += len(x.Source) + len(x.Value)
+		}
+	default:
+		n = len(msg.String())
+	}
+
+	return (n + 3) / 4
+}
+
+// The budget memory fits the context window built by other memory into
+// the token budget. The stratum and the prompt are always retained,
+// the oldest observations are dropped first.
+type Budget struct {
+	thinker.Memory
+	budget    int
+	estimator Estimator
+}
+
+var _ thinker.Memory = (*Budget)(nil)
+
+// Creates new budget memory that limits the context window of the memory.
+// The Approx estimator is used if estimator is nil.
+func NewBudget(budget int, estimator Estimator, memory thinker.Memory) *Budget {
+	if estimator == nil {
+		estimator = Approx
+	}
+
+	return &Budget{Memory: memory, budget: budget, estimator: estimator}
+}
+
+// Builds the context window for LLM using incoming prompt.
+func (b *Budget) Context(prompt chatter.Message) []chatter.Message {
+	return window(b.budget, b.estimator, b.Memory.Context(prompt), prompt != nil)
+}
+
+// window drops the oldest messages from the context until it fits the budget.
+// The window never starts with the reply or tool answer so that the sequence
+// remains valid conversation, the tool invocation and its result are either
+// retained or dropped together. The window exceeds the budget only if the
+// stratum and the prompt do not fit.
+//
+// The pending tool result requires the chain of tool invocations. The chain
+// retains the prompt initiating it and the pending invocation, intermediate
+// invocations and their results are dropped by whole pairs, the oldest first.
+// Older messages are retained only if the whole chain fits.
+func window(budget int, estimator Estimator, seq []chatter.Message, withPrompt bool) []chatter.Message {
+	if budget <= 0 {
+		return seq
+	}
+
+	head, tail := 0, len(seq)
+	if tail > 0 {
+		if _, ok := seq[0].(chatter.Stratum); ok {
+			head = 1
+		}
+	}
+	if withPrompt && tail > head {
+		tail--
+	}
+
+	chain := tail
+	if withPrompt && tail < len(seq) && isToolResult(seq[tail]) {
+		for at := tail - 1; at >= head; at-- {
+			if _, ok := seq[at].(*chatter.Reply); !ok && !isToolResult(seq[at]) {
+				chain = at
+				break
+			}
+		}
+	}
+
+	used := 0
+	for _, msg := range seq[:head] {
+		used += estimator(msg)
+	}
+	for _, msg := range seq[tail:] {
+		used += estimator(msg)
+	}
+
+	pairs := tail
+	if chain < tail {
+		used += estimator(seq[chain])
+		if pairs-1 > chain {
+			pairs--
+			used += estimator(seq[pairs])
+		}
+		for pairs-2 > chain {
+			n := estimator(seq[pairs-2]) + estimator(seq[pairs-1])
+			if used+n > budget {
+				break
+			}
+			used += n
+			pairs -= 2
+		}
+	}
+
+	from := chain
+	for from > head && pairs <= chain+1 {
+		n := estimator(seq[from-1])
+		if used+n > budget {
+			break
+		}
+		used += n
+		from--
+	}
+
+	for from < chain {
+		switch seq[from].(type) {
+		case *chatter.Reply, *chatter.Answer:
+			from++
+			continue
+		}
+		break
+	}
+
+	if from == head && pairs <= chain+1 {
+		return seq
+	}
+
+	ctx := make([]chatter.Message, 0, head+len(seq)-from)
+	ctx = append(ctx, seq[:head]...)
+	ctx = append(ctx, seq[from:min(chain+1, pairs)]...)
+	ctx = append(ctx, seq[pairs:]...)
+	return ctx
+}
@@ -0,0 +1,198 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// each message costs exactly 1 token
+func unit(chatter.Message) int { return 1 }
+
+func TestApprox(t *testing.T) {
+	answer := &chatter.Answer{
+		Yield: []chatter.Json{{Source: "fs_read", Value: []byte(`{"toolOutput":"abc"}`)}},
+	}
+	invoke := &chatter.Reply{
+		Stage: chatter.LLM_INVOKE,
+		Content: []chatter.Content{
+			chatter.Invoke{Cmd: "fs_read", Args: chatter.Json{Value: []byte(`{"path":"/a"}`)}},
+		},
+	}
+
+	it.Then(t).Should(
+		it.Equal(Approx(chatter.Text("12345678")), 2),
+		it.Equal(Approx(chatter.Text("123456789")), 3),
+		it.Equal(Approx(answer), 7),
+		it.Equal(Approx(invoke), 5),
+	)
+}
+
+func TestStreamBudget(t *testing.T) {
+	t.Run("Fits", func(t *testing.T) {
+		s := NewStream(INFINITE, "role.").WithBudget(6, unit)
+		observe(s, "a")
+		observe(s, "b")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("c"))).Equal("role.", "a?", "a.", "b?", "b.", "c"),
+		)
+	})
+
+	t.Run("DropsOldest", func(t *testing.T) {
+		s := NewStream(INFINITE, "role.").WithBudget(5, unit)
+		observe(s, "a")
+		observe(s, "b")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("c"))).Equal("role.", "b?", "b.", "c"),
+		)
+	})
+
+	t.Run("KeepsStratumAndPrompt", func(t *testing.T) {
+		s := NewStream(INFINITE, "role.").WithBudget(1, unit)
+		observe(s, "a")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("c"))).Equal("role.", "c"),
+		)
+	})
+
+	t.Run("WithoutPrompt", func(t *testing.T) {
+		s := NewStream(INFINITE, "").WithBudget(3, unit)
+		observe(s, "a")
+		observe(s, "b")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, nil)).Equal("b?", "b."),
+		)
+	})
+
+	t.Run("Decorator", func(t *testing.T) {
+		s := NewBudget(5, unit, NewStream(INFINITE, "role."))
+		observe(s, "a")
+		observe(s, "b")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("c"))).Equal("role.", "b?", "b.", "c"),
+		)
+	})
+
+	t.Run("Approx", func(t *testing.T) {
+		s := NewStream(INFINITE, "").WithBudget(5, nil)
+		observe(s, "aaaa")
+		observe(s, "bbbb")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("c"))).Equal("bbbb?", "bbbb.", "c"),
+		)
+	})
+}
+
+func TestWindowBudget(t *testing.T) {
+	invoke := &chatter.Reply{
+		Stage: chatter.LLM_INVOKE,
+		Content: []chatter.Content{
+			chatter.Invoke{Cmd: "fs_read", Args: chatter.Json{ID: "1", Value: []byte(`{"path":"/a"}`)}},
+		},
+	}
+	answer := &chatter.Answer{
+		Yield: []chatter.Json{{ID: "1", Source: "fs_read", Value: []byte(`{"toolOutput":"abcdefgh"}`)}},
+	}
+
+	sum := func(seq []chatter.Message) int {
+		n := 0
+		for _, msg := range seq {
+			n += Approx(msg)
+		}
+		return n
+	}
+
+	// the stratum, the prompt and the pending invocation with the prompt initiating it
+	fixed := func(seq []chatter.Message) int {
+		n := Approx(seq[0]) + Approx(seq[len(seq)-1])
+		if isToolResult(seq[len(seq)-1]) {
+			at := len(seq) - 2
+			n += Approx(seq[at])
+			for at > 1 {
+				at--
+				if _, ok := seq[at].(*chatter.Reply); !ok && !isToolResult(seq[at]) {
+					break
+				}
+			}
+			n += Approx(seq[at])
+		}
+		return n
+	}
+
+	t.Run("PendingChain", func(t *testing.T) {
+		s := NewStream(INFINITE, "role.")
+		observe(s, "aaaaaaaaaaaaaaaa")
+		s.Commit(thinker.NewObservation(&chatter.Prompt{Task: "c?"}, invoke))
+		s.Commit(thinker.NewObservation(answer, invoke))
+		s.Commit(thinker.NewObservation(answer, invoke))
+
+		seq := s.Context(answer)
+		ctx := window(fixed(seq)+Approx(invoke)+Approx(answer), Approx, seq, true)
+		it.Then(t).Should(
+			it.Seq(ctx).Equal(seq[0], seq[3], seq[6], seq[7], seq[8], seq[9]),
+		)
+
+		ctx = window(fixed(seq), Approx, seq, true)
+		it.Then(t).Should(
+			it.Seq(ctx).Equal(seq[0], seq[3], seq[8], seq[9]),
+		)
+	})
+
+	t.Run("Property", func(t *testing.T) {
+		rnd := rand.New(rand.NewPCG(1, 2))
+		text := func() string { return strings.Repeat("x", 1+rnd.IntN(32)) }
+
+		for range 256 {
+			s := NewStream(INFINITE, "role.")
+			for range rnd.IntN(8) {
+				if rnd.IntN(2) == 0 {
+					observe(s, text())
+					continue
+				}
+				s.Commit(thinker.NewObservation(&chatter.Prompt{Task: chatter.Task(text())}, invoke))
+				s.Commit(thinker.NewObservation(answer, &chatter.Reply{Content: []chatter.Content{chatter.Text(text())}}))
+			}
+
+			var prompt chatter.Message = chatter.Text(text())
+			if rnd.IntN(2) == 0 {
+				s.Commit(thinker.NewObservation(&chatter.Prompt{Task: chatter.Task(text())}, invoke))
+				for range rnd.IntN(4) {
+					s.Commit(thinker.NewObservation(answer, invoke))
+				}
+				prompt = answer
+			}
+
+			seq := s.Context(prompt)
+			budget := 1 + rnd.IntN(sum(seq)+1)
+			if fixed(seq) > budget {
+				continue
+			}
+
+			ctx := window(budget, Approx, seq, true)
+			_, reply := ctx[1].(*chatter.Reply)
+			it.Then(t).Should(
+				it.Less(sum(ctx), budget+1),
+				it.Equal(ctx[len(ctx)-1], prompt),
+				it.True(!reply && !isToolResult(ctx[1])),
+			)
+		}
+	})
+}
@@ -0,0 +1,269 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"iter"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fogfish/guid/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+const journal = "memory.jsonl"
+
+// The durable memory retains agent's observations in the time ordered
+// sequence (see Stream) and appends every observation to JSONL log within
+// the local directory. The log is reloaded when memory is created, so that
+// observations survive restarts of the application. The log is compacted
+// when observations are evicted, so that evicted observations are erased
+// from the disk.
+//
+// The provider specific message of tool invocation (chatter.Invoke) is
+// recovered from the log as json.RawMessage.
+type Durable struct {
+	mu     sync.Mutex
+	stream *Stream
+	path   string
+	fd     *os.File
+}
+
+var (
+	_ thinker.Memory    = (*Durable)(nil)
+	_ thinker.Inspector = (*Durable)(nil)
+)
+
+// Creates new durable memory at the directory, reloading observations from
+// the existing log. The log is compacted to the retained observations.
+func NewDurable(dir string, cap int, stratum chatter.Stratum) (*Durable, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &Durable{
+		stream: NewStream(cap, stratum),
+		path:   filepath.Join(dir, journal),
+	}
+
+	fd, err := os.Open(s.path)
+	switch {
+	case err == nil:
+		seq, err := decodeJournal(fd, true)
+		fd.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range seq {
+			s.stream.Commit(e)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close the log.
+func (s *Durable) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.fd.Close()
+}
+
+// intentional the loss of memories, including facts, information and experiences
+func (s *Durable) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stream.Reset()
+	if err := s.compact(); err != nil {
+		slog.Warn("failed to reset memory log", "path", s.path, "err", err)
+	}
+}
+
+// Commit new observation into memory and append it to the log. The log is
+// written ahead, the observation is encoded before it is shared with readers.
+func (s *Durable) Commit(e *thinker.Observation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.append(e); err != nil {
+		slog.Warn("failed to append memory log", "path", s.path, "err", err)
+	}
+
+	n := s.stream.Len()
+	s.stream.Commit(e)
+	if s.stream.Len() > n {
+		return
+	}
+
+	if err := s.compact(); err != nil {
+		slog.Warn("failed to compact memory log", "path", s.path, "err", err)
+	}
+}
+
+// Builds the context window for LLM using incoming prompt.
+func (s *Durable) Context(prompt chatter.Message) []chatter.Message {
+	return s.stream.Context(prompt)
+}
+
+// Iterates retained observations in the order of commits.
+func (s *Durable) Observations() iter.Seq[*thinker.Observation] {
+	return s.stream.Observations()
+}
+
+// Lookup the retained observation by its id.
+func (s *Durable) Lookup(id guid.K) (*thinker.Observation, bool) {
+	return s.stream.Lookup(id)
+}
+
+// Iterates retained observations created within the time range [from, to).
+func (s *Durable) Range(from, to time.Time) iter.Seq[*thinker.Observation] {
+	return s.stream.Range(from, to)
+}
+
+// Forget retained observations, the log is rewritten so that forgotten
+// observations are erased from the disk.
+func (s *Durable) Forget(ids ...guid.K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stream.Forget(ids...)
+	if err := s.compact(); err != nil {
+		slog.Warn("failed to compact memory log", "path", s.path, "err", err)
+	}
+}
+
+// Number of retained observations.
+func (s *Durable) Len() int { return s.stream.Len() }
+
+// Estimated number of tokens used by retained observations.
+func (s *Durable) Tokens() int { return s.stream.Tokens() }
+
+// Snapshot writes retained observations as JSONL.
+func (s *Durable) Snapshot(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.snapshot(w)
+}
+
+// Restore replaces retained observations with ones read from JSONL snapshot.
+// The log is rewritten with restored observations.
+func (s *Durable) Restore(r io.Reader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seq, err := decodeJournal(r, false)
+	if err != nil {
+		return err
+	}
+
+	s.stream.Reset()
+	for _, e := range seq {
+		s.stream.Commit(e)
+	}
+
+	return s.compact()
+}
+
+func (s *Durable) snapshot(w io.Writer) error {
+	s.stream.mu.Lock()
+	defer s.stream.mu.Unlock()
+
+	for _, id := range s.stream.commits {
+		bin, err := encodeObservation(s.stream.heap[id])
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(append(bin, '\n')); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reads JSONL, the truncated tail is tolerated while recovering the log
+// after the crash.
+func decodeJournal(r io.Reader, tolerant bool) ([]*thinker.Observation, error) {
+	seq := make([]*thinker.Observation, 0)
+	dec := json.NewDecoder(r)
+	for {
+		var obj jsonObservation
+		err := dec.Decode(&obj)
+		switch {
+		case err == io.EOF:
+			return seq, nil
+		case tolerant && errors.Is(err, io.ErrUnexpectedEOF):
+			return seq, nil
+		case err != nil:
+			return nil, err
+		}
+
+		e, err := decodeObservation(obj)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, e)
+	}
+}
+
+func (s *Durable) append(e *thinker.Observation) error {
+	bin, err := encodeObservation(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.fd.Write(append(bin, '\n'))
+	return err
+}
+
+// atomically rewrites the log with retained observations
+func (s *Durable) compact() error {
+	tmp := s.path + ".tmp"
+	fd, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := s.snapshot(fd); err != nil {
+		fd.Close()
+		return err
+	}
+
+	if err := fd.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+
+	if s.fd != nil {
+		s.fd.Close()
+	}
+
+	s.fd, err = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0644)
+	return err
+}
@@ -0,0 +1,252 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+func TestDurable(t *testing.T) {
+	t.Run("Reload", func(t *testing.T) {
+		dir := t.TempDir()
+		a, err := NewDurable(dir, INFINITE, "role.")
+		it.Then(t).Must(it.Nil(err))
+		observe(a, "a")
+		observe(a, "b")
+		it.Then(t).Must(it.Nil(a.Close()))
+
+		b, err := NewDurable(dir, INFINITE, "role.")
+		it.Then(t).Must(it.Nil(err))
+		defer b.Close()
+
+		it.Then(t).Should(
+			it.Seq(windowOf(b, nil)).Equal("role.", "a?", "a.", "b?", "b."),
+		)
+	})
+
+	t.Run("ReloadWithCap", func(t *testing.T) {
+		dir := t.TempDir()
+		a, err := NewDurable(dir, INFINITE, "")
+		it.Then(t).Must(it.Nil(err))
+		observe(a, "a")
+		observe(a, "b")
+		observe(a, "c")
+		it.Then(t).Must(it.Nil(a.Close()))
+
+		b, err := NewDurable(dir, 1, "")
+		it.Then(t).Must(it.Nil(err))
+		defer b.Close()
+
+		it.Then(t).Should(
+			it.Seq(windowOf(b, nil)).Equal("c?", "c."),
+		)
+	})
+
+	t.Run("TruncatedLog", func(t *testing.T) {
+		dir := t.TempDir()
+		a, err := NewDurable(dir, INFINITE, "")
+		it.Then(t).Must(it.Nil(err))
+		observe(a, "a")
+		it.Then(t).Must(it.Nil(a.Close()))
+
+		fd, err := os.OpenFile(filepath.Join(dir, journal), os.O_APPEND|os.O_WRONLY, 0644)
+		it.Then(t).Must(it.Nil(err))
+		fd.WriteString(`{"created":`)
+		fd.Close()
+
+		b, err := NewDurable(dir, INFINITE, "")
+		it.Then(t).Must(it.Nil(err))
+		defer b.Close()
+
+		it.Then(t).Should(
+			it.Seq(windowOf(b, nil)).Equal("a?", "a."),
+		)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		dir := t.TempDir()
+		a, err := NewDurable(dir, INFINITE, "")
+		it.Then(t).Must(it.Nil(err))
+		observe(a, "a")
+		a.Reset()
+		observe(a, "b")
+		it.Then(t).Must(it.Nil(a.Close()))
+
+		b, err := NewDurable(dir, INFINITE, "")
+		it.Then(t).Must(it.Nil(err))
+		defer b.Close()
+
+		it.Then(t).Should(
+			it.Seq(windowOf(b, nil)).Equal("b?", "b."),
+		)
+	})
+
+	t.Run("SnapshotRestore", func(t *testing.T) {
+		a, err := NewDurable(t.TempDir(), INFINITE, "")
+		it.Then(t).Must(it.Nil(err))
+		defer a.Close()
+		observe(a, "a")
+		observe(a, "b")
+
+		var buf bytes.Buffer
+		it.Then(t).Must(it.Nil(a.Snapshot(&buf)))
+
+		dir := t.TempDir()
+		b, err := NewDurable(dir, INFINITE, "")
+		it.Then(t).Must(it.Nil(err))
+		observe(b, "c")
+		it.Then(t).Must(it.Nil(b.Restore(&buf)))
+		it.Then(t).Should(
+			it.Seq(windowOf(b, nil)).Equal("a?", "a.", "b?", "b."),
+		)
+		it.Then(t).Must(it.Nil(b.Close()))
+
+		c, err := NewDurable(dir, INFINITE, "")
+		it.Then(t).Must(it.Nil(err))
+		defer c.Close()
+		it.Then(t).Should(
+			it.Seq(windowOf(c, nil)).Equal("a?", "a.", "b?", "b."),
+		)
+	})
+
+	t.Run("CompactOnEvict", func(t *testing.T) {
+		dir := t.TempDir()
+		a, err := NewDurable(dir, 1, "")
+		it.Then(t).Must(it.Nil(err))
+		defer a.Close()
+		observe(a, "a")
+		observe(a, "b")
+
+		fd, err := os.Open(filepath.Join(dir, journal))
+		it.Then(t).Must(it.Nil(err))
+		defer fd.Close()
+
+		seq, err := decodeJournal(fd, false)
+		it.Then(t).Must(it.Nil(err))
+		it.Then(t).Should(
+			it.Equal(len(seq), 1),
+			it.Equal(seq[0].Query.Content.String(), "b?"),
+		)
+	})
+
+	t.Run("RestoreInvalid", func(t *testing.T) {
+		a, err := NewDurable(t.TempDir(), INFINITE, "")
+		it.Then(t).Must(it.Nil(err))
+		defer a.Close()
+		observe(a, "a")
+
+		err = a.Restore(bytes.NewBufferString(`{"query":{"type":"unknown"}}`))
+		it.Then(t).ShouldNot(it.Nil(err))
+		it.Then(t).Should(
+			it.Seq(windowOf(a, nil)).Equal("a?", "a."),
+		)
+	})
+}
+
+func TestJsonInvoke(t *testing.T) {
+	invoke := func(msg any) *thinker.Observation {
+		return thinker.NewObservation(
+			chatter.Text("a?"),
+			&chatter.Reply{
+				Stage: chatter.LLM_INVOKE,
+				Content: []chatter.Content{
+					chatter.Invoke{
+						Cmd:     "fs_read",
+						Args:    chatter.Json{ID: "1", Value: []byte(`{"path":"/a"}`)},
+						Message: msg,
+					},
+				},
+			},
+		)
+	}
+
+	t.Run("Message", func(t *testing.T) {
+		msg := struct {
+			ID string `json:"toolUseId"`
+		}{ID: "1"}
+
+		bin, err := encodeObservation(invoke(msg))
+		it.Then(t).Must(it.Nil(err))
+
+		seq, err := decodeJournal(bytes.NewBuffer(bin), false)
+		it.Then(t).Must(it.Nil(err))
+		it.Then(t).Should(
+			it.Equiv(seq[0].Reply.Content, invoke(json.RawMessage(`{"toolUseId":"1"}`)).Reply.Content),
+		)
+	})
+
+	t.Run("NoMessage", func(t *testing.T) {
+		e := invoke(nil)
+		bin, err := encodeObservation(e)
+		it.Then(t).Must(it.Nil(err))
+
+		seq, err := decodeJournal(bytes.NewBuffer(bin), false)
+		it.Then(t).Must(it.Nil(err))
+		it.Then(t).Should(
+			it.Equiv(seq[0], e),
+		)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := encodeObservation(invoke(make(chan int)))
+		it.Then(t).ShouldNot(it.Nil(err))
+	})
+}
+
+func TestJsonObservation(t *testing.T) {
+	var prompt chatter.Prompt
+	prompt.WithTask("task.")
+	prompt.WithGuide("guide", "g.")
+	prompt.WithRules("rules", "r.")
+	prompt.WithFeedback("feedback", "f.")
+	prompt.WithExample("in", "out")
+	prompt.WithContext("context", "c.")
+	prompt.WithInput("input", "i.")
+	prompt.WithBlob("blob", "b.")
+
+	reply := &chatter.Reply{
+		Stage: chatter.LLM_INVOKE,
+		Usage: chatter.Usage{InputTokens: 10, ReplyTokens: 20},
+		Content: []chatter.Content{
+			chatter.Text("text."),
+			chatter.Invoke{Cmd: "fs_read", Args: chatter.Json{ID: "1", Value: []byte(`{"path":"/a"}`)}},
+			chatter.Vector{1.0, 2.0},
+			chatter.Binary{Name: "a", Type: "b", Data: []byte("c")},
+		},
+	}
+
+	answer := &chatter.Answer{
+		Yield: []chatter.Json{{ID: "1", Source: "fs_read", Value: []byte(`{"toolOutput":"abc"}`)}},
+	}
+
+	for _, e := range []*thinker.Observation{
+		thinker.NewObservation(&prompt, reply),
+		thinker.NewObservation(answer, chatter.Text("text")),
+		thinker.NewObservation(chatter.Task("task"), chatter.Stratum("stratum")),
+	} {
+		e.Reply.Importance = 0.5
+		bin, err := encodeObservation(e)
+		it.Then(t).Must(it.Nil(err))
+
+		seq, err := decodeJournal(bytes.NewBuffer(bin), false)
+		it.Then(t).Must(it.Nil(err))
+		it.Then(t).Should(
+			it.Equal(len(seq), 1),
+			it.Equiv(seq[0], e),
+		)
+	}
+}
@@ -0,0 +1,322 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fogfish/guid/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// Triple is the fact about entities: subject, predicate, object.
+type Triple struct {
+	Subject   string `json:"subject"`
+	Predicate string `json:"predicate"`
+	Object    string `json:"object"`
+}
+
+func (t Triple) String() string {
+	return t.Subject + " " + t.Predicate + " " + t.Object
+}
+
+// Extractor extracts facts from the observation.
+type Extractor interface {
+	Extract(*thinker.Observation) ([]Triple, error)
+}
+
+// The graph memory retains knowledge graph of entities and relations instead
+// of observations. Facts are extracted from each committed observation.
+// The context window contains only facts connected to the entities
+// mentioned in the incoming prompt, followed by the recent observations
+// kept verbatim.
+type Graph struct {
+	mu        sync.Mutex
+	extractor Extractor
+	stratum   chatter.Stratum
+	facts     []Triple
+	known     map[Triple]struct{}
+	entities  map[string][]int
+	depth     int
+	heap      map[guid.K]*thinker.Observation
+	commits   []guid.K
+	recent    int
+}
+
+var _ thinker.Memory = (*Graph)(nil)
+
+// Creates new graph memory using the extractor of facts.
+func NewGraph(extractor Extractor, stratum chatter.Stratum) *Graph {
+	return &Graph{
+		extractor: extractor,
+		stratum:   stratum,
+		facts:     make([]Triple, 0),
+		known:     make(map[Triple]struct{}),
+		entities:  make(map[string][]int),
+		depth:     1,
+		heap:      make(map[guid.K]*thinker.Observation),
+		commits:   make([]guid.K, 0),
+		recent:    1,
+	}
+}
+
+// Configures the number of hops from the mentioned entities (default 1).
+func (s *Graph) WithDepth(depth int) *Graph {
+	s.depth = depth
+	return s
+}
+
+// Configures the number of recent observations kept verbatim (default 1).
+// The open tool invocation is always kept, LLM providers reject the tool
+// result without the preceding invocation.
+func (s *Graph) WithRecent(recent int) *Graph {
+	s.recent = recent
+	return s
+}
+
+// intentional the loss of memories, including facts, information and experiences
+func (s *Graph) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.facts = make([]Triple, 0)
+	s.known = make(map[Triple]struct{})
+	s.entities = make(map[string][]int)
+	s.heap = make(map[guid.K]*thinker.Observation)
+	s.commits = make([]guid.K, 0)
+}
+
+// Commit new observation into memory, the observation is discarded once
+// facts are extracted and it is not recent anymore. Facts are lost if
+// extractor fails.
+func (s *Graph) Commit(e *thinker.Observation) {
+	seq, err := s.extractor.Extract(e)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.heap[e.Created] = e
+	s.commits = append(s.commits, e.Created)
+
+	// the tool invocation and its result are evicted together
+	if at := align(s.heap, s.commits, len(s.commits)-max(s.recent, 1)); at > 0 {
+		for _, id := range s.commits[:at] {
+			delete(s.heap, id)
+		}
+		s.commits = s.commits[at:]
+	}
+
+	if err != nil {
+		return
+	}
+
+	for _, fact := range seq {
+		fact = Triple{
+			Subject:   strings.TrimSpace(fact.Subject),
+			Predicate: strings.TrimSpace(fact.Predicate),
+			Object:    strings.TrimSpace(fact.Object),
+		}
+		if len(fact.Subject) == 0 || len(fact.Predicate) == 0 || len(fact.Object) == 0 {
+			continue
+		}
+
+		if _, has := s.known[fact]; has {
+			continue
+		}
+
+		at := len(s.facts)
+		s.facts = append(s.facts, fact)
+		s.known[fact] = struct{}{}
+		s.index(fact.Subject, at)
+		s.index(fact.Object, at)
+	}
+}
+
+func (s *Graph) index(entity string, at int) {
+	key := strings.ToLower(entity)
+	if seq := s.entities[key]; len(seq) == 0 || seq[len(seq)-1] != at {
+		s.entities[key] = append(seq, at)
+	}
+}
+
+// Builds the context window for LLM using incoming prompt. The window contains
+// facts connected to entities mentioned in the prompt, in the order of commits,
+// and recent observations.
+func (s *Graph) Context(prompt chatter.Message) []chatter.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seq := make([]chatter.Message, 0)
+	if len(s.stratum) > 0 {
+		seq = append(seq, s.stratum)
+	}
+
+	if facts := s.recall(prompt); len(facts) > 0 {
+		var note chatter.Prompt
+		note.WithContext("Known facts", facts...)
+		seq = append(seq, &note)
+	}
+
+	recent := s.commits[len(s.commits)-min(max(s.recent, 0), len(s.commits)):]
+	for _, id := range expand(s.heap, s.commits, recent, isToolResult(prompt), len(recent)) {
+		evidence := s.heap[id]
+		evidence.Accessed = guid.G(guid.Clock)
+
+		seq = append(seq, evidence.Query.Content)
+		seq = append(seq, evidence.Reply.Content)
+	}
+
+	if prompt != nil {
+		seq = append(seq, prompt)
+	}
+
+	return seq
+}
+
+// selects facts connected to entities mentioned in the prompt
+func (s *Graph) recall(prompt chatter.Message) []string {
+	if prompt == nil {
+		return nil
+	}
+
+	text := strings.ToLower(prompt.String())
+	frontier := make([]string, 0)
+	visited := make(map[string]struct{})
+	for entity := range s.entities {
+		if mentions(text, entity) {
+			frontier = append(frontier, entity)
+			visited[entity] = struct{}{}
+		}
+	}
+
+	selected := make([]bool, len(s.facts))
+	for hop := 0; hop < s.depth && len(frontier) > 0; hop++ {
+		next := make([]string, 0)
+		for _, entity := range frontier {
+			for _, at := range s.entities[entity] {
+				selected[at] = true
+				for _, x := range []string{s.facts[at].Subject, s.facts[at].Object} {
+					key := strings.ToLower(x)
+					if _, has := visited[key]; !has {
+						visited[key] = struct{}{}
+						next = append(next, key)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+
+	facts := make([]string, 0)
+	for at, fact := range s.facts {
+		if selected[at] {
+			facts = append(facts, fact.String())
+		}
+	}
+
+	return facts
+}
+
+// true if text mentions the entity as the whole word(s)
+func mentions(text, entity string) bool {
+	for from := 0; from < len(text); {
+		at := strings.Index(text[from:], entity)
+		if at == -1 {
+			return false
+		}
+		at += from
+
+		before, _ := utf8.DecodeLastRuneInString(text[:at])
+		after, _ := utf8.DecodeRuneInString(text[at+len(entity):])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+
+		from = at + 1
+	}
+
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+//------------------------------------------------------------------------------
+
+// LLM-based extractor, it asks LLM to list facts as triples.
+type LLMExtractor struct {
+	llm chatter.Chatter
+}
+
+var _ Extractor = (*LLMExtractor)(nil)
+
+// Creates new LLM-based extractor of facts.
+func NewLLMExtractor(llm chatter.Chatter) *LLMExtractor {
+	return &LLMExtractor{llm: llm}
+}
+
+// Extract facts from the observation.
+func (x *LLMExtractor) Extract(e *thinker.Observation) ([]Triple, error) {
+	var prompt chatter.Prompt
+	prompt.WithTask("Extract entities and relations between them from the following piece of memory as the list of facts.")
+	prompt.WithRules(
+		"Strictly follow the rules",
+		"Reply with one fact per line formatted as: subject | predicate | object.",
+		"Use short canonical names of entities, the same entity must have the same name.",
+		"Reply with empty text if there are no facts.",
+	)
+	prompt.WithBlob("Memory", "User: "+transcript(e.Query.Content)+"\nAssistant: "+transcript(e.Reply.Content))
+
+	reply, err := x.llm.Prompt(context.Background(), []chatter.Message{&prompt})
+	if err != nil {
+		return nil, err
+	}
+
+	seq := make([]Triple, 0)
+	for _, line := range strings.Split(reply.String(), "\n") {
+		spo := strings.Split(line, "|")
+		if len(spo) != 3 {
+			continue
+		}
+		seq = append(seq, Triple{
+			Subject:   strings.TrimSpace(spo[0]),
+			Predicate: strings.TrimSpace(spo[1]),
+			Object:    strings.TrimSpace(spo[2]),
+		})
+	}
+
+	return seq, nil
+}
+
+// Agent that extracts facts from the text (e.g. agent.Automata).
+type FactAgent interface {
+	Prompt(context.Context, string, ...chatter.Opt) ([]Triple, error)
+}
+
+// Adapts the agent as extractor of facts, the agent receives the transcript
+// of observation.
+func NewAgentExtractor(agent FactAgent) Extractor {
+	return agentExtractor{agent: agent}
+}
+
+type agentExtractor struct {
+	agent FactAgent
+}
+
+func (x agentExtractor) Extract(e *thinker.Observation) ([]Triple, error) {
+	return x.agent.Prompt(context.Background(),
+		"User: "+transcript(e.Query.Content)+"\nAssistant: "+transcript(e.Reply.Content),
+	)
+}
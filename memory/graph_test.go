@@ -0,0 +1,155 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// extracts facts "subject|predicate|object" separated by ";" from the reply
+type extractor struct{}
+
+func (extractor) Extract(e *thinker.Observation) ([]Triple, error) {
+	seq := make([]Triple, 0)
+	for _, fact := range strings.Split(e.Reply.Content.String(), ";") {
+		spo := strings.Split(fact, "|")
+		if len(spo) == 3 {
+			seq = append(seq, Triple{Subject: spo[0], Predicate: spo[1], Object: spo[2]})
+		}
+	}
+	return seq, nil
+}
+
+// agent replies with the fixed facts
+type factAgent []Triple
+
+func (a factAgent) Prompt(context.Context, string, ...chatter.Opt) ([]Triple, error) {
+	return a, nil
+}
+
+func TestGraph(t *testing.T) {
+	facts := func(m thinker.Memory, prompt string) string {
+		seq := m.Context(chatter.Text(prompt))
+		if len(seq) < 2 {
+			return ""
+		}
+		return seq[len(seq)-2].String()
+	}
+
+	t.Run("Connected", func(t *testing.T) {
+		s := NewGraph(extractor{}, "").WithRecent(0)
+		commit(s, "?", "Alice|works at|Acme;Bob|works at|Initech")
+		commit(s, "?", "Acme|located in|Berlin")
+
+		it.Then(t).Should(
+			it.String(facts(s, "Where does alice work?")).Contain("Alice works at Acme"),
+		).ShouldNot(
+			it.String(facts(s, "Where does alice work?")).Contain("Berlin"),
+			it.String(facts(s, "Where does alice work?")).Contain("Bob"),
+		)
+	})
+
+	t.Run("Depth", func(t *testing.T) {
+		s := NewGraph(extractor{}, "").WithDepth(2).WithRecent(0)
+		commit(s, "?", "Alice|works at|Acme;Bob|works at|Initech")
+		commit(s, "?", "Acme|located in|Berlin")
+
+		it.Then(t).Should(
+			it.String(facts(s, "Where does alice work?")).Contain("Alice works at Acme"),
+			it.String(facts(s, "Where does alice work?")).Contain("Acme located in Berlin"),
+		).ShouldNot(
+			it.String(facts(s, "Where does alice work?")).Contain("Bob"),
+		)
+	})
+
+	t.Run("WholeWord", func(t *testing.T) {
+		s := NewGraph(extractor{}, "role.").WithRecent(0)
+		commit(s, "?", "AI|is|field")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("she said"))).Equal("role.", "she said"),
+		)
+	})
+
+	t.Run("Dedup", func(t *testing.T) {
+		s := NewGraph(extractor{}, "")
+		commit(s, "?", "Alice|knows|Bob")
+		commit(s, "?", " Alice | knows | Bob ")
+
+		it.Then(t).Should(
+			it.Equal(len(s.facts), 1),
+		)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		s := NewGraph(extractor{}, "")
+		commit(s, "?", "Alice|knows|Bob")
+		s.Reset()
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("Alice"))).Equal("Alice"),
+		)
+	})
+
+	t.Run("Recent", func(t *testing.T) {
+		s := NewGraph(extractor{}, "")
+		commit(s, "?", "Alice|knows|Bob")
+		commit(s, "?", "Bob|likes|tea")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("Bob"))[1:]).Equal("?", "Bob|likes|tea", "Bob"),
+		)
+	})
+
+	t.Run("ToolResult", func(t *testing.T) {
+		s := NewGraph(extractor{}, "").WithRecent(0)
+		commit(s, "?", "Alice|knows|Bob")
+		s.Commit(thinker.NewObservation(chatter.Text("Bob"), &chatter.Reply{
+			Stage:   chatter.LLM_INVOKE,
+			Content: []chatter.Content{chatter.Invoke{Cmd: "search", Args: chatter.Json{ID: "id"}}},
+		}))
+
+		answer := &chatter.Answer{Yield: []chatter.Json{{ID: "id", Value: []byte(`{}`)}}}
+		seq := s.Context(answer)
+		it.Then(t).Must(it.Equal(len(seq), 3))
+		it.Then(t).Should(
+			it.Equal(seq[0].String(), "Bob"),
+			it.Equal(seq[1].(*chatter.Reply).Stage, chatter.LLM_INVOKE),
+			it.True(seq[2] == chatter.Message(answer)),
+		)
+	})
+
+	t.Run("AgentExtractor", func(t *testing.T) {
+		s := NewGraph(NewAgentExtractor(factAgent{{"Alice", "knows", "Bob"}}), "").WithRecent(0)
+		commit(s, "?", "")
+
+		it.Then(t).Should(
+			it.String(facts(s, "Bob")).Contain("Alice knows Bob"),
+		)
+	})
+}
+
+func TestLLMExtractor(t *testing.T) {
+	e := thinker.NewObservation(chatter.Text("a"), chatter.Text("b"))
+	seq, err := NewLLMExtractor(constant("Alice | knows | Bob\ninvalid\nBob|likes|tea")).Extract(e)
+
+	it.Then(t).Should(
+		it.Nil(err),
+		it.Seq(seq).Equal(
+			Triple{"Alice", "knows", "Bob"},
+			Triple{"Bob", "likes", "tea"},
+		),
+	)
+}
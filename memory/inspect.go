@@ -0,0 +1,157 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"iter"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fogfish/guid/v2"
+	"github.com/kshard/thinker"
+)
+
+// The archive retains observations in the heap, ordered by commits. Memories
+// embed it to implement thinker.Inspector, its lock guards the state of
+// the memory. Iterators operate on the snapshot of observations taken under
+// the lock.
+type archive struct {
+	mu        sync.Mutex
+	heap      map[guid.K]*thinker.Observation
+	commits   []guid.K
+	estimator Estimator
+}
+
+func newArchive() archive {
+	return archive{
+		heap:    make(map[guid.K]*thinker.Observation),
+		commits: make([]guid.K, 0),
+	}
+}
+
+// drops all observations, the caller holds the lock
+func (a *archive) reset() {
+	a.heap = make(map[guid.K]*thinker.Observation)
+	a.commits = make([]guid.K, 0)
+}
+
+// appends the observation, the caller holds the lock
+func (a *archive) append(e *thinker.Observation) {
+	a.heap[e.Created] = e
+	a.commits = append(a.commits, e.Created)
+}
+
+// Iterates retained observations in the order of commits.
+func (a *archive) Observations() iter.Seq[*thinker.Observation] {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return values(snapshot(a.heap, a.commits))
+}
+
+// Lookup the retained observation by its id.
+func (a *archive) Lookup(id guid.K) (*thinker.Observation, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	e, has := a.heap[id]
+	return e, has
+}
+
+// Iterates retained observations created within the time range [from, to).
+func (a *archive) Range(from, to time.Time) iter.Seq[*thinker.Observation] {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return values(within(snapshot(a.heap, a.commits), from, to))
+}
+
+// Forget retained observations, the tool invocation and its result are
+// forgotten together.
+func (a *archive) Forget(ids ...guid.K) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.commits = forget(a.heap, a.commits, ids)
+}
+
+// Number of retained observations.
+func (a *archive) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.commits)
+}
+
+// Estimated number of tokens used by retained observations.
+func (a *archive) Tokens() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return tokens(a.estimator, snapshot(a.heap, a.commits))
+}
+
+func snapshot(heap map[guid.K]*thinker.Observation, commits []guid.K) []*thinker.Observation {
+	seq := make([]*thinker.Observation, len(commits))
+	for i, id := range commits {
+		seq[i] = heap[id]
+	}
+	return seq
+}
+
+// observations created within the time range [from, to)
+func within(seq []*thinker.Observation, from, to time.Time) []*thinker.Observation {
+	return slices.DeleteFunc(seq, func(e *thinker.Observation) bool {
+		t := guid.EpochT(e.Created)
+		return t.Before(from) || !t.Before(to)
+	})
+}
+
+// removes observations from the heap, expanding them to whole units of tool
+// invocation, returns retained commits
+func forget(heap map[guid.K]*thinker.Observation, commits []guid.K, ids []guid.K) []guid.K {
+	known := make([]guid.K, 0, len(ids))
+	for _, id := range ids {
+		if _, has := heap[id]; has && !slices.Contains(known, id) {
+			known = append(known, id)
+		}
+	}
+
+	drop := expand(heap, commits, known, false, -1)
+	if len(drop) == 0 {
+		return commits
+	}
+
+	for _, id := range drop {
+		delete(heap, id)
+	}
+
+	return slices.DeleteFunc(slices.Clone(commits), func(id guid.K) bool {
+		_, has := heap[id]
+		return !has
+	})
+}
+
+// estimates tokens used by observations
+func tokens(estimator Estimator, seq []*thinker.Observation) int {
+	if estimator == nil {
+		estimator = Approx
+	}
+
+	n := 0
+	for _, e := range seq {
+		n += estimator(e.Query.Content) + estimator(e.Reply.Content)
+	}
+	return n
+}
+
+func values(seq []*thinker.Observation) iter.Seq[*thinker.Observation] {
+	return slices.Values(seq)
+}
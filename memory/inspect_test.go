@@ -0,0 +1,160 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fogfish/guid/v2"
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+func TestInspector(t *testing.T) {
+	replies := func(m thinker.Inspector) []string {
+		seq := make([]string, 0)
+		for e := range m.Observations() {
+			seq = append(seq, e.Reply.Content.String())
+		}
+		return seq
+	}
+
+	durable, err := NewDurable(t.TempDir(), INFINITE, "")
+	it.Then(t).Must(it.Nil(err))
+	defer durable.Close()
+
+	for name, m := range map[string]interface {
+		thinker.Memory
+		thinker.Inspector
+	}{
+		"Stream":    NewStream(INFINITE, ""),
+		"Semantic":  NewSemantic(1, "", nil),
+		"Summary":   NewSummary(&summarizer{}, 10, 1, ""),
+		"Retrieval": NewRetrieval(1, "", nil),
+		"Durable":   durable,
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := observe(m, "a")
+			b := observe(m, "b")
+			c := observe(m, "c")
+
+			found, has := m.Lookup(b.Created)
+			_, none := m.Lookup(guid.G(guid.Clock))
+			it.Then(t).Should(
+				it.Seq(replies(m)).Equal("a.", "b.", "c."),
+				it.Equal(m.Len(), 3),
+				it.Equal(m.Tokens(), 6),
+				it.True(has),
+				it.Equal(found, b),
+				it.True(!none),
+			)
+
+			ts := slices.Collect(m.Range(guid.EpochT(a.Created), time.Now().Add(time.Second)))
+			it.Then(t).Should(
+				it.Seq(ts).Equal(a, b, c),
+			)
+
+			m.Forget(a.Created, c.Created)
+			it.Then(t).Should(
+				it.Seq(replies(m)).Equal("b."),
+				it.Equal(m.Len(), 1),
+			)
+		})
+	}
+}
+
+func TestInspectorConcurrent(t *testing.T) {
+	durable, err := NewDurable(t.TempDir(), INFINITE, "")
+	it.Then(t).Must(it.Nil(err))
+	defer durable.Close()
+
+	for name, m := range map[string]interface {
+		thinker.Memory
+		thinker.Inspector
+	}{
+		"Stream":    NewStream(INFINITE, ""),
+		"Semantic":  NewSemantic(1, "", nil),
+		"Summary":   NewSummary(&summarizer{}, 4, 1, ""),
+		"Retrieval": NewRetrieval(1, "", nil),
+		"Durable":   durable,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for range 4 {
+				wg.Go(func() {
+					for range 128 {
+						e := observe(m, "a")
+						m.Context(chatter.Text("b"))
+						m.Forget(e.Created)
+						m.Len()
+					}
+				})
+			}
+			wg.Wait()
+
+			it.Then(t).Should(
+				it.Equal(m.Len(), 0),
+			)
+		})
+	}
+}
+
+func TestInspectorForget(t *testing.T) {
+	t.Run("ToolCall", func(t *testing.T) {
+		s := NewStream(INFINITE, "")
+		a := thinker.NewObservation(&chatter.Prompt{Task: "a?"}, &chatter.Reply{Stage: chatter.LLM_INVOKE})
+		b := thinker.NewObservation(&chatter.Answer{}, chatter.Text("a."))
+		c := thinker.NewObservation(&chatter.Prompt{Task: "c?"}, chatter.Text("c."))
+		s.Commit(a)
+		s.Commit(b)
+		s.Commit(c)
+
+		s.Forget(b.Created)
+		it.Then(t).Should(
+			it.Seq(slices.Collect(s.Observations())).Equal(c),
+		)
+	})
+
+	t.Run("Durable", func(t *testing.T) {
+		dir := t.TempDir()
+		a, err := NewDurable(dir, INFINITE, "")
+		it.Then(t).Must(it.Nil(err))
+		x := thinker.NewObservation(&chatter.Prompt{Task: "x?"}, chatter.Text("x."))
+		y := thinker.NewObservation(&chatter.Prompt{Task: "y?"}, chatter.Text("y."))
+		a.Commit(x)
+		a.Commit(y)
+		a.Forget(x.Created)
+		it.Then(t).Must(it.Nil(a.Close()))
+
+		b, err := NewDurable(dir, INFINITE, "")
+		it.Then(t).Must(it.Nil(err))
+		defer b.Close()
+
+		_, has := b.Lookup(x.Created)
+		it.Then(t).Should(
+			it.Equal(b.Len(), 1),
+			it.True(!has),
+		)
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		s := NewStream(INFINITE, "")
+		s.Commit(thinker.NewObservation(chatter.Text("a"), chatter.Text("a")))
+
+		now := time.Now()
+		it.Then(t).Should(
+			it.Equal(len(slices.Collect(s.Range(now.Add(-time.Hour), now.Add(time.Hour)))), 1),
+			it.Equal(len(slices.Collect(s.Range(now.Add(time.Hour), now.Add(2*time.Hour)))), 0),
+		)
+	})
+}
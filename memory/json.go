@@ -0,0 +1,311 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fogfish/guid/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/float8"
+	"github.com/kshard/thinker"
+)
+
+// The stable JSON serialization of observations. Messages and content blocks
+// are tagged by type. The provider specific message of chatter.Invoke is
+// serialized as JSON and recovered as json.RawMessage, the observation is not
+// serializable if the message is not.
+type jsonObservation struct {
+	Created         guid.K          `json:"created"`
+	Accessed        *guid.K         `json:"accessed,omitempty"`
+	Query           jsonMessage     `json:"query"`
+	QueryRelevance  []float8.Float8 `json:"queryRelevance,omitempty"`
+	Reply           jsonMessage     `json:"reply"`
+	ReplyRelevance  []float8.Float8 `json:"replyRelevance,omitempty"`
+	ReplyImportance float64         `json:"replyImportance,omitempty"`
+}
+
+type jsonMessage struct {
+	Type    string         `json:"type"`
+	Text    string         `json:"text,omitempty"`
+	Stage   chatter.Stage  `json:"stage,omitempty"`
+	Usage   *chatter.Usage `json:"usage,omitempty"`
+	Content []jsonContent  `json:"content,omitempty"`
+	Yield   []chatter.Json `json:"yield,omitempty"`
+}
+
+type jsonContent struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+type jsonInvoke struct {
+	chatter.Invoke
+	Message json.RawMessage `json:"message,omitempty"`
+}
+
+const (
+	typeText     = "text"
+	typeStratum  = "stratum"
+	typeTask     = "task"
+	typePrompt   = "prompt"
+	typeReply    = "reply"
+	typeAnswer   = "answer"
+	typeJson     = "json"
+	typeGuide    = "guide"
+	typeRules    = "rules"
+	typeFeedback = "feedback"
+	typeExample  = "example"
+	typeContext  = "context"
+	typeInput    = "input"
+	typeBlob     = "blob"
+	typeInvoke   = "invoke"
+	typeVector   = "vector"
+	typeBinary   = "binary"
+)
+
+func encodeObservation(e *thinker.Observation) ([]byte, error) {
+	query, err := encodeMessage(e.Query.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := encodeMessage(e.Reply.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := jsonObservation{
+		Created:         e.Created,
+		Query:           query,
+		QueryRelevance:  e.Query.Relevance,
+		Reply:           reply,
+		ReplyRelevance:  e.Reply.Relevance,
+		ReplyImportance: e.Reply.Importance,
+	}
+	if e.Accessed != (guid.K{}) {
+		obj.Accessed = &e.Accessed
+	}
+
+	return json.Marshal(obj)
+}
+
+func decodeObservation(obj jsonObservation) (*thinker.Observation, error) {
+	query, err := decodeMessage(obj.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := decodeMessage(obj.Reply)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &thinker.Observation{
+		Created: obj.Created,
+		Query:   thinker.Input{Content: query, Relevance: obj.QueryRelevance},
+		Reply: thinker.Reply{
+			Content:    reply,
+			Relevance:  obj.ReplyRelevance,
+			Importance: obj.ReplyImportance,
+		},
+	}
+	if obj.Accessed != nil {
+		e.Accessed = *obj.Accessed
+	}
+
+	return e, nil
+}
+
+func encodeMessage(msg chatter.Message) (jsonMessage, error) {
+	switch v := msg.(type) {
+	case nil:
+		return jsonMessage{}, nil
+	case chatter.Text:
+		return jsonMessage{Type: typeText, Text: string(v)}, nil
+	case chatter.Stratum:
+		return jsonMessage{Type: typeStratum, Text: string(v)}, nil
+	case chatter.Task:
+		return jsonMessage{Type: typeTask, Text: string(v)}, nil
+	case *chatter.Prompt:
+		content, err := encodeContent(v.Content)
+		if err != nil {
+			return jsonMessage{}, err
+		}
+		return jsonMessage{Type: typePrompt, Text: string(v.Task), Content: content}, nil
+	case *chatter.Reply:
+		content, err := encodeContent(v.Content)
+		if err != nil {
+			return jsonMessage{}, err
+		}
+		usage := v.Usage
+		return jsonMessage{Type: typeReply, Stage: v.Stage, Usage: &usage, Content: content}, nil
+	case *chatter.Answer:
+		return jsonMessage{Type: typeAnswer, Yield: v.Yield}, nil
+	default:
+		return jsonMessage{}, fmt.Errorf("unsupported message type %T", msg)
+	}
+}
+
+func decodeMessage(obj jsonMessage) (chatter.Message, error) {
+	switch obj.Type {
+	case "":
+		return nil, nil
+	case typeText:
+		return chatter.Text(obj.Text), nil
+	case typeStratum:
+		return chatter.Stratum(obj.Text), nil
+	case typeTask:
+		return chatter.Task(obj.Text), nil
+	case typePrompt:
+		content, err := decodeContent(obj.Content)
+		if err != nil {
+			return nil, err
+		}
+		return &chatter.Prompt{Task: chatter.Task(obj.Text), Content: content}, nil
+	case typeReply:
+		content, err := decodeContent(obj.Content)
+		if err != nil {
+			return nil, err
+		}
+		reply := &chatter.Reply{Stage: obj.Stage, Content: content}
+		if obj.Usage != nil {
+			reply.Usage = *obj.Usage
+		}
+		return reply, nil
+	case typeAnswer:
+		return &chatter.Answer{Yield: obj.Yield}, nil
+	default:
+		return nil, fmt.Errorf("unsupported message type %s", obj.Type)
+	}
+}
+
+func encodeContent(seq []chatter.Content) ([]jsonContent, error) {
+	content := make([]jsonContent, len(seq))
+	for i, c := range seq {
+		var (
+			kind string
+			val  any
+		)
+
+		switch v := c.(type) {
+		case chatter.Text:
+			kind, val = typeText, string(v)
+		case chatter.Task:
+			kind, val = typeTask, string(v)
+		case chatter.Json:
+			kind, val = typeJson, v
+		case chatter.Guide:
+			kind, val = typeGuide, v
+		case chatter.Rules:
+			kind, val = typeRules, v
+		case chatter.Feedback:
+			kind, val = typeFeedback, v
+		case chatter.Example:
+			kind, val = typeExample, v
+		case chatter.Context:
+			kind, val = typeContext, v
+		case chatter.Input:
+			kind, val = typeInput, v
+		case chatter.Blob:
+			kind, val = typeBlob, v
+		case chatter.Invoke:
+			inv := jsonInvoke{Invoke: v}
+			if v.Message != nil {
+				raw, err := json.Marshal(v.Message)
+				if err != nil {
+					return nil, fmt.Errorf("unsupported message of invoke @%s: %w", v.Cmd, err)
+				}
+				inv.Message = raw
+			}
+			kind, val = typeInvoke, inv
+		case chatter.Vector:
+			kind, val = typeVector, []float32(v)
+		case chatter.Binary:
+			kind, val = typeBinary, v
+		default:
+			return nil, fmt.Errorf("unsupported content type %T", c)
+		}
+
+		raw, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		content[i] = jsonContent{Type: kind, Value: raw}
+	}
+
+	return content, nil
+}
+
+func decodeContent(seq []jsonContent) ([]chatter.Content, error) {
+	content := make([]chatter.Content, len(seq))
+	for i, c := range seq {
+		var err error
+		switch c.Type {
+		case typeText:
+			content[i], err = decodeAs[chatter.Text](c.Value)
+		case typeTask:
+			content[i], err = decodeAs[chatter.Task](c.Value)
+		case typeJson:
+			content[i], err = decodeAs[chatter.Json](c.Value)
+		case typeGuide:
+			content[i], err = decodeAs[chatter.Guide](c.Value)
+		case typeRules:
+			content[i], err = decodeAs[chatter.Rules](c.Value)
+		case typeFeedback:
+			content[i], err = decodeAs[chatter.Feedback](c.Value)
+		case typeExample:
+			content[i], err = decodeAs[chatter.Example](c.Value)
+		case typeContext:
+			content[i], err = decodeAs[chatter.Context](c.Value)
+		case typeInput:
+			content[i], err = decodeAs[chatter.Input](c.Value)
+		case typeBlob:
+			content[i], err = decodeAs[chatter.Blob](c.Value)
+		case typeInvoke:
+			content[i], err = decodeInvoke(c.Value)
+		case typeVector:
+			content[i], err = decodeAs[chatter.Vector](c.Value)
+		case typeBinary:
+			content[i], err = decodeAs[chatter.Binary](c.Value)
+		default:
+			err = fmt.Errorf("unsupported content type %s", c.Type)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return content, nil
+}
+
+func decodeInvoke(raw json.RawMessage) (chatter.Content, error) {
+	var val jsonInvoke
+	if err := json.Unmarshal(raw, &val); err != nil {
+		return nil, err
+	}
+
+	inv := val.Invoke
+	if len(val.Message) > 0 {
+		inv.Message = val.Message
+	}
+
+	return inv, nil
+}
+
+func decodeAs[C chatter.Content](raw json.RawMessage) (chatter.Content, error) {
+	var val C
+	if err := json.Unmarshal(raw, &val); err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
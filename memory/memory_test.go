@@ -0,0 +1,38 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// commits the observation of the query and the reply
+func commit(m thinker.Memory, query, reply string) *thinker.Observation {
+	e := thinker.NewObservation(
+		&chatter.Prompt{Task: chatter.Task(query)},
+		&chatter.Reply{Content: []chatter.Content{chatter.Text(reply)}},
+	)
+	m.Commit(e)
+	return e
+}
+
+// commits the observation "text?" replied by "text."
+func observe(m thinker.Memory, text string) *thinker.Observation {
+	return commit(m, text+"?", text+".")
+}
+
+// builds the context window for the prompt as the sequence of strings
+func windowOf(m thinker.Memory, prompt chatter.Message) []string {
+	seq := make([]string, 0)
+	for _, x := range m.Context(prompt) {
+		seq = append(seq, x.String())
+	}
+	return seq
+}
@@ -0,0 +1,281 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fogfish/guid/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/float8"
+	"github.com/kshard/thinker"
+)
+
+// Rater estimates the importance of observation on the interval [0, 1].
+type Rater interface {
+	Rate(*thinker.Observation) (float64, error)
+}
+
+// Scoring configures the retrieval function, the score of observation is
+// the weighted sum of recency, importance and relevance. Each signal is
+// normalized on the interval [0, 1] across observations before weighting.
+type Scoring struct {
+	// Weight of recency signal
+	Recency float64
+
+	// Weight of importance signal
+	Importance float64
+
+	// Weight of relevance signal
+	Relevance float64
+
+	// Exponential decay factor of recency per hour since the last access
+	Decay float64
+}
+
+// Default scoring, all signals are equally weighted.
+var DefaultScoring = Scoring{Recency: 1.0, Importance: 1.0, Relevance: 1.0, Decay: 0.995}
+
+// The retrieval memory implements the retrieval model of generative agents
+// (see https://arxiv.org/abs/2304.03442). It retains all of the agent's
+// observations but recalls only top-k observations ranked by the weighted
+// mix of recency, importance and relevance to the incoming prompt.
+type Retrieval struct {
+	archive
+	stratum  chatter.Stratum
+	embedder Embedder
+	rater    Rater
+	score    Similarity
+	scoring  Scoring
+	topk     int
+}
+
+var (
+	_ thinker.Memory    = (*Retrieval)(nil)
+	_ thinker.Inspector = (*Retrieval)(nil)
+)
+
+// Creates new retrieval memory that recalls top-k observations. The embedder
+// is optional, relevance signal is not used without it.
+func NewRetrieval(topk int, stratum chatter.Stratum, embedder Embedder) *Retrieval {
+	return &Retrieval{
+		archive:  newArchive(),
+		stratum:  stratum,
+		embedder: embedder,
+		score:    Cosine,
+		scoring:  DefaultScoring,
+		topk:     topk,
+	}
+}
+
+// Configures weights of the retrieval function.
+func (s *Retrieval) WithScoring(scoring Scoring) *Retrieval {
+	s.scoring = scoring
+	return s
+}
+
+// Configures the rater, which estimates importance of observations on commit.
+// Without rater, the importance given by the observation is used.
+func (s *Retrieval) WithRater(rater Rater) *Retrieval {
+	s.rater = rater
+	return s
+}
+
+// Configures the similarity function used for relevance (default Cosine).
+func (s *Retrieval) WithSimilarity(score Similarity) *Retrieval {
+	s.score = score
+	return s
+}
+
+// intentional the loss of memories, including facts, information and experiences
+func (s *Retrieval) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reset()
+}
+
+// Commit new observation into memory, the importance and relevance vectors
+// are estimated unless the observation already has them.
+func (s *Retrieval) Commit(e *thinker.Observation) {
+	if len(e.Query.Relevance) == 0 {
+		e.Query.Relevance = embed(s.embedder, e.Query.Content)
+	}
+
+	if len(e.Reply.Relevance) == 0 {
+		e.Reply.Relevance = embed(s.embedder, e.Reply.Content)
+	}
+
+	if s.rater != nil && e.Reply.Importance == 0.0 {
+		if importance, err := s.rater.Rate(e); err == nil {
+			e.Reply.Importance = importance
+		}
+	}
+
+	if e.Accessed == (guid.K{}) {
+		e.Accessed = e.Created
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.append(e)
+}
+
+// Builds the context window for LLM using incoming prompt. The window contains
+// top-k observations with the highest retrieval score, in the order of commits.
+func (s *Retrieval) Context(prompt chatter.Message) []chatter.Message {
+	vec := embed(s.embedder, prompt)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seq := make([]chatter.Message, 0)
+	if len(s.stratum) > 0 {
+		seq = append(seq, s.stratum)
+	}
+
+	now := guid.G(guid.Clock)
+	recall := s.recall(guid.EpochT(now), vec)
+	for _, id := range expand(s.heap, s.commits, recall, isToolResult(prompt), s.topk) {
+		evidence := s.heap[id]
+		evidence.Accessed = now
+
+		seq = append(seq, evidence.Query.Content)
+		seq = append(seq, evidence.Reply.Content)
+	}
+
+	if prompt != nil {
+		seq = append(seq, prompt)
+	}
+
+	return seq
+}
+
+// selects top-k observations, preserving the commit order
+func (s *Retrieval) recall(now time.Time, vec []float8.Float8) []guid.K {
+	if s.topk < 0 || len(s.commits) <= s.topk {
+		return s.commits
+	}
+
+	recency := make([]float64, len(s.commits))
+	importance := make([]float64, len(s.commits))
+	relevance := make([]float64, len(s.commits))
+	for i, id := range s.commits {
+		evidence := s.heap[id]
+		hours := now.Sub(guid.EpochT(evidence.Accessed)).Hours()
+		recency[i] = math.Pow(s.scoring.Decay, max(hours, 0.0))
+		importance[i] = evidence.Reply.Importance
+		if len(vec) > 0 {
+			relevance[i] = max(
+				s.score(vec, evidence.Query.Relevance),
+				s.score(vec, evidence.Reply.Relevance),
+			)
+		}
+	}
+
+	normalize(recency)
+	normalize(importance)
+	normalize(relevance)
+
+	type rank struct {
+		at    int
+		score float64
+	}
+
+	seq := make([]rank, len(s.commits))
+	for i := range s.commits {
+		seq[i] = rank{
+			at: i,
+			score: s.scoring.Recency*recency[i] +
+				s.scoring.Importance*importance[i] +
+				s.scoring.Relevance*relevance[i],
+		}
+	}
+
+	// the most recent observation wins if scores are equal
+	slices.SortStableFunc(seq, func(a, b rank) int {
+		switch {
+		case a.score > b.score:
+			return -1
+		case a.score < b.score:
+			return 1
+		default:
+			return b.at - a.at
+		}
+	})
+
+	seq = seq[:s.topk]
+	slices.SortFunc(seq, func(a, b rank) int { return a.at - b.at })
+
+	ids := make([]guid.K, len(seq))
+	for i, r := range seq {
+		ids[i] = s.commits[r.at]
+	}
+
+	return ids
+}
+
+// min-max normalization on the interval [0, 1]
+func normalize(seq []float64) {
+	lo, hi := slices.Min(seq), slices.Max(seq)
+	if hi == lo {
+		for i := range seq {
+			seq[i] = 0.0
+		}
+		return
+	}
+
+	for i, x := range seq {
+		seq[i] = (x - lo) / (hi - lo)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// LLM-based rater, it asks LLM to rate the poignancy of observation.
+type LLMRater struct {
+	llm chatter.Chatter
+}
+
+var _ Rater = (*LLMRater)(nil)
+
+// Creates new LLM-based rater of the observation importance.
+func NewLLMRater(llm chatter.Chatter) *LLMRater {
+	return &LLMRater{llm: llm}
+}
+
+// Rate the importance of observation on the interval [0, 1].
+func (r *LLMRater) Rate(e *thinker.Observation) (float64, error) {
+	var prompt chatter.Prompt
+	prompt.WithTask("On the scale of 1 to 10, where 1 is purely mundane (e.g., greetings, routine steps) and 10 is extremely poignant (e.g., key facts, decisions, failures), rate the likely importance of the following piece of memory.")
+	prompt.WithRules(
+		"Strictly follow the rules",
+		"Reply with a single integer number only.",
+	)
+	prompt.WithBlob("Memory", "User: "+transcript(e.Query.Content)+"\nAssistant: "+transcript(e.Reply.Content))
+
+	reply, err := r.llm.Prompt(context.Background(), []chatter.Message{&prompt})
+	if err != nil {
+		return 0.0, err
+	}
+
+	val, err := strconv.Atoi(strings.Trim(reply.String(), " \t\r\n."))
+	if err != nil {
+		return 0.0, fmt.Errorf("invalid importance rate: %w", err)
+	}
+
+	return float64(min(max(val, 1), 10)) / 10.0, nil
+}
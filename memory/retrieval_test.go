@@ -0,0 +1,140 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fogfish/guid/v2"
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// rates observation by the number of exclamation marks
+type rater struct{}
+
+func (rater) Rate(e *thinker.Observation) (float64, error) {
+	return float64(strings.Count(e.Reply.Content.String(), "!")) / 10.0, nil
+}
+
+// replies with the constant text
+type constant string
+
+func (constant) Usage() chatter.Usage { return chatter.Usage{} }
+
+func (c constant) Prompt(context.Context, []chatter.Message, ...chatter.Opt) (*chatter.Reply, error) {
+	return &chatter.Reply{Content: []chatter.Content{chatter.Text(c)}}, nil
+}
+
+func TestRetrieval(t *testing.T) {
+	t.Run("Commit", func(t *testing.T) {
+		s := NewRetrieval(1, "", embedder{"cat", "dog"}).WithRater(rater{})
+		e := commit(s, "cat?", "cat!!!")
+
+		it.Then(t).Should(
+			it.Equal(e.Reply.Importance, 0.3),
+			it.Equal(e.Accessed, e.Created),
+			it.Equal(len(e.Query.Relevance), 2),
+		)
+	})
+
+	t.Run("Importance", func(t *testing.T) {
+		s := NewRetrieval(1, "", nil).
+			WithRater(rater{}).
+			WithScoring(Scoring{Importance: 1.0, Decay: 0.995})
+		commit(s, "a?", "a!")
+		commit(s, "b?", "b!!!")
+		commit(s, "c?", "c!!")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("d"))).Equal("b?", "b!!!", "d"),
+		)
+	})
+
+	t.Run("Recency", func(t *testing.T) {
+		s := NewRetrieval(1, "", nil).
+			WithScoring(Scoring{Recency: 1.0, Decay: 0.5})
+		a := commit(s, "a?", "a.")
+		b := commit(s, "b?", "b.")
+		a.Accessed = guid.FromT(time.Now().Add(-1 * time.Hour))
+		b.Accessed = guid.FromT(time.Now().Add(-5 * time.Hour))
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("c"))).Equal("a?", "a.", "c"),
+		)
+	})
+
+	t.Run("Relevance", func(t *testing.T) {
+		s := NewRetrieval(1, "", embedder{"cat", "dog", "car"}).
+			WithScoring(Scoring{Relevance: 1.0, Decay: 0.995})
+		commit(s, "cat?", "cat.")
+		commit(s, "dog?", "dog.")
+		commit(s, "car?", "car.")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("dog"))).Equal("dog?", "dog.", "dog"),
+		)
+	})
+
+	t.Run("Weighted", func(t *testing.T) {
+		s := NewRetrieval(2, "role.", embedder{"cat", "dog", "car"}).WithRater(rater{})
+		cat := commit(s, "cat?", "cat!!!")
+		dog := commit(s, "dog?", "dog.")
+		car := commit(s, "car?", "car.")
+
+		// equally recent, the rank does not depend on the tick of clock
+		at := guid.FromT(time.Now().Add(-1 * time.Hour))
+		cat.Accessed, dog.Accessed, car.Accessed = at, at, at
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("car"))).Equal("role.", "cat?", "cat!!!", "car?", "car.", "car"),
+		)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		s := NewRetrieval(1, "role.", nil)
+		commit(s, "a?", "a.")
+		s.Reset()
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("b"))).Equal("role.", "b"),
+		)
+	})
+}
+
+func TestLLMRater(t *testing.T) {
+	e := thinker.NewObservation(chatter.Text("a"), chatter.Text("b"))
+
+	t.Run("Rate", func(t *testing.T) {
+		val, err := NewLLMRater(constant("7")).Rate(e)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(val, 0.7),
+		)
+	})
+
+	t.Run("Clamp", func(t *testing.T) {
+		val, err := NewLLMRater(constant("42")).Rate(e)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(val, 1.0),
+		)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := NewLLMRater(constant("high")).Rate(e)
+		it.Then(t).ShouldNot(
+			it.Nil(err),
+		)
+	})
+}
@@ -0,0 +1,229 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"context"
+	"math"
+	"slices"
+
+	"github.com/fogfish/guid/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/float8"
+	"github.com/kshard/thinker"
+)
+
+// Embedder transforms text into embedding vector. The interface is compatible
+// with `aio.Embedder` from github.com/kshard/chatter.
+type Embedder interface {
+	Embedding(context.Context, string) ([]float32, int, error)
+}
+
+// Similarity scores two embedding vectors, higher score means closer vectors.
+type Similarity func(a, b []float8.Float8) float64
+
+// Cosine similarity of embedding vectors.
+func Cosine(a, b []float8.Float8) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0.0
+	}
+
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(float8.ToFloat32(a[i])), float64(float8.ToFloat32(b[i]))
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+
+	if na == 0.0 || nb == 0.0 {
+		return 0.0
+	}
+
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// Dot product of embedding vectors, use it with normalized embeddings.
+func Dot(a, b []float8.Float8) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0.0
+	}
+
+	var dot float64
+	for i := range a {
+		dot += float64(float8.ToFloat32(a[i])) * float64(float8.ToFloat32(b[i]))
+	}
+
+	return dot
+}
+
+// The semantic memory retains all of the agent's observations but recalls
+// only top-k observations relevant to the incoming prompt. The relevance is
+// estimated using embedding vectors.
+type Semantic struct {
+	archive
+	stratum  chatter.Stratum
+	embedder Embedder
+	score    Similarity
+	topk     int
+}
+
+var (
+	_ thinker.Memory    = (*Semantic)(nil)
+	_ thinker.Inspector = (*Semantic)(nil)
+)
+
+// Creates new semantic memory that recalls top-k relevant observations.
+func NewSemantic(topk int, stratum chatter.Stratum, embedder Embedder) *Semantic {
+	return &Semantic{
+		archive:  newArchive(),
+		stratum:  stratum,
+		embedder: embedder,
+		score:    Cosine,
+		topk:     topk,
+	}
+}
+
+// Configures the similarity function used to rank observations (default Cosine).
+func (s *Semantic) WithSimilarity(score Similarity) *Semantic {
+	s.score = score
+	return s
+}
+
+// intentional the loss of memories, including facts, information and experiences
+func (s *Semantic) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reset()
+}
+
+// Commit new observation into memory, the relevance vectors of query and reply
+// are computed unless the observation already has them.
+func (s *Semantic) Commit(e *thinker.Observation) {
+	if len(e.Query.Relevance) == 0 {
+		e.Query.Relevance = s.embed(e.Query.Content)
+	}
+
+	if len(e.Reply.Relevance) == 0 {
+		e.Reply.Relevance = s.embed(e.Reply.Content)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.append(e)
+}
+
+// Builds the context window for LLM using incoming prompt. The window contains
+// top-k observations most relevant to the prompt, in the order of commits.
+// The most recent observations are used if relevance of prompt is unknown.
+func (s *Semantic) Context(prompt chatter.Message) []chatter.Message {
+	vec := s.embed(prompt)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seq := make([]chatter.Message, 0)
+	if len(s.stratum) > 0 {
+		seq = append(seq, s.stratum)
+	}
+
+	for _, id := range expand(s.heap, s.commits, s.recall(vec), isToolResult(prompt), s.topk) {
+		evidence := s.heap[id]
+		evidence.Accessed = guid.G(guid.Clock)
+
+		seq = append(seq, evidence.Query.Content)
+		seq = append(seq, evidence.Reply.Content)
+	}
+
+	if prompt != nil {
+		seq = append(seq, prompt)
+	}
+
+	return seq
+}
+
+// selects top-k observations, preserving the commit order
+func (s *Semantic) recall(vec []float8.Float8) []guid.K {
+	if s.topk < 0 || len(s.commits) <= s.topk {
+		return s.commits
+	}
+
+	if len(vec) == 0 {
+		return s.commits[len(s.commits)-s.topk:]
+	}
+
+	type rank struct {
+		at    int
+		score float64
+	}
+
+	seq := make([]rank, len(s.commits))
+	for i, id := range s.commits {
+		evidence := s.heap[id]
+		seq[i] = rank{
+			at: i,
+			score: max(
+				s.score(vec, evidence.Query.Relevance),
+				s.score(vec, evidence.Reply.Relevance),
+			),
+		}
+	}
+
+	// the most recent observation wins if scores are equal
+	slices.SortStableFunc(seq, func(a, b rank) int {
+		switch {
+		case a.score > b.score:
+			return -1
+		case a.score < b.score:
+			return 1
+		default:
+			return b.at - a.at
+		}
+	})
+
+	seq = seq[:s.topk]
+	slices.SortFunc(seq, func(a, b rank) int { return a.at - b.at })
+
+	ids := make([]guid.K, len(seq))
+	for i, r := range seq {
+		ids[i] = s.commits[r.at]
+	}
+
+	return ids
+}
+
+func (s *Semantic) embed(msg chatter.Message) []float8.Float8 {
+	return embed(s.embedder, msg)
+}
+
+// embeds message text, nil is returned if embedding is not possible
+func embed(embedder Embedder, msg chatter.Message) []float8.Float8 {
+	if embedder == nil || msg == nil {
+		return nil
+	}
+
+	text := msg.String()
+	if len(text) == 0 {
+		return nil
+	}
+
+	vec, _, err := embedder.Embedding(context.Background(), text)
+	if err != nil {
+		return nil
+	}
+
+	f8s := make([]float8.Float8, len(vec))
+	for i, x := range vec {
+		f8s[i] = float8.ToFloat8(x)
+	}
+
+	return f8s
+}
@@ -0,0 +1,102 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/float8"
+)
+
+// bag of words embedding over fixed vocabulary
+type embedder []string
+
+func (vocab embedder) Embedding(_ context.Context, text string) ([]float32, int, error) {
+	vec := make([]float32, len(vocab))
+	for i, w := range vocab {
+		if strings.Contains(text, w) {
+			vec[i] = 1.0
+		}
+	}
+	return vec, len(text), nil
+}
+
+func TestSemantic(t *testing.T) {
+	t.Run("Commit", func(t *testing.T) {
+		s := NewSemantic(1, "role.", embedder{"cat", "dog", "car"})
+		e := observe(s, "dog")
+
+		it.Then(t).Should(
+			it.Seq(s.commits).Equal(e.Created),
+			it.Seq(e.Query.Relevance).Equal(0, float8.ToFloat8(1.0), 0),
+			it.Seq(e.Reply.Relevance).Equal(0, float8.ToFloat8(1.0), 0),
+		)
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		s := NewSemantic(1, "role.", embedder{"cat", "dog", "car"})
+		observe(s, "cat")
+		observe(s, "dog")
+		observe(s, "car")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, &chatter.Prompt{Task: "dog or"})).Equal("role.", "dog?", "dog.", "dog or"),
+			it.Seq(windowOf(s, &chatter.Prompt{Task: "cat or"})).Equal("role.", "cat?", "cat.", "cat or"),
+		)
+	})
+
+	t.Run("ContextOrder", func(t *testing.T) {
+		s := NewSemantic(2, "", embedder{"cat", "dog", "car"})
+		observe(s, "car")
+		observe(s, "dog")
+		observe(s, "cat")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, &chatter.Prompt{Task: "cat and car"})).Equal("car?", "car.", "cat?", "cat.", "cat and car"),
+		)
+	})
+
+	t.Run("ContextRecent", func(t *testing.T) {
+		s := NewSemantic(1, "", embedder{"cat", "dog", "car"})
+		observe(s, "cat")
+		observe(s, "dog")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, nil)).Equal("dog?", "dog."),
+		)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		s := NewSemantic(1, "role.", embedder{"cat", "dog", "car"})
+		observe(s, "cat")
+		s.Reset()
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, &chatter.Prompt{Task: "cat"})).Equal("role.", "cat"),
+		)
+	})
+}
+
+func TestSimilarity(t *testing.T) {
+	a := float8.ToSlice8([]float32{1.0, 0.0, 1.0, 0.0})
+	b := float8.ToSlice8([]float32{1.0, 1.0, 0.0, 0.0})
+
+	it.Then(t).Should(
+		it.True(math.Abs(Cosine(a, a)-1.0) < 1e-6),
+		it.True(math.Abs(Cosine(a, b)-0.5) < 1e-6),
+		it.Equal(Cosine(a, nil), 0.0),
+		it.Equal(Dot(a, b), 1.0),
+		it.Equal(Dot(a, a), 2.0),
+	)
+}
@@ -0,0 +1,233 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+type sessionKey struct{}
+
+// Binds the session id to the context. Session aware memories isolate
+// observations of each session.
+func WithSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, id)
+}
+
+// Returns the session id bound to the context, empty string if none.
+func SessionOf(ctx context.Context) string {
+	if id, ok := ctx.Value(sessionKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+type session struct {
+	memory   thinker.Memory
+	accessed time.Time
+	runs     int
+}
+
+// memory of the session, it refreshes the access time on every use and
+// tracks runs of agents using the session (see thinker.Lifecycle).
+type sessionMemory struct {
+	thinker.Memory
+	owner *Sessions
+	entry *session
+}
+
+func (m *sessionMemory) Commit(e *thinker.Observation) {
+	m.owner.touch(m.entry, 0)
+	m.Memory.Commit(e)
+}
+
+func (m *sessionMemory) Context(prompt chatter.Message) []chatter.Message {
+	m.owner.touch(m.entry, 0)
+	return m.Memory.Context(prompt)
+}
+
+func (m *sessionMemory) OnStart(ctx context.Context) {
+	m.owner.touch(m.entry, 1)
+	thinker.OnStart(ctx, m.Memory)
+}
+
+func (m *sessionMemory) OnEnd(ctx context.Context) {
+	thinker.OnEnd(ctx, m.Memory)
+	m.owner.touch(m.entry, -1)
+}
+
+func (m *sessionMemory) OnAbort(ctx context.Context, err error) {
+	thinker.OnAbort(ctx, err, m.Memory)
+	m.owner.touch(m.entry, -1)
+}
+
+// memory of the session exposing thinker.Inspector of the underlying memory
+type sessionInspector struct {
+	*sessionMemory
+	thinker.Inspector
+}
+
+// The session memory manager isolates observations of concurrent users of
+// a single agent. The memory of each session is created on demand using
+// the factory; sessions are evicted after the idle timeout or when the number
+// of live sessions exceeds the capacity, the least recently used first.
+// Evicted memories are closed if they implement io.Closer. Sessions in use
+// by runs of agents are not evicted.
+//
+// Agents bind the memory to the session at the beginning of every prompt
+// (see thinker.SessionMemory). Used directly, it operates on the default
+// session with the empty id.
+type Sessions struct {
+	mu       sync.Mutex
+	factory  func(id string) thinker.Memory
+	sessions map[string]*session
+	cap      int
+	idle     time.Duration
+	clock    func() time.Time
+}
+
+var _ thinker.SessionMemory = (*Sessions)(nil)
+
+// Creates new session memory manager. The capacity limits the number of live
+// sessions, the idle timeout evicts inactive sessions. Zero or negative values
+// disable corresponding eviction.
+func NewSessions(cap int, idle time.Duration, factory func(id string) thinker.Memory) *Sessions {
+	return &Sessions{
+		factory:  factory,
+		sessions: make(map[string]*session),
+		cap:      cap,
+		idle:     idle,
+		clock:    time.Now,
+	}
+}
+
+// Number of live sessions.
+func (s *Sessions) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.sessions)
+}
+
+// Resolves the memory of the session bound to the context.
+func (s *Sessions) Session(ctx context.Context) thinker.Memory {
+	return s.lookup(SessionOf(ctx))
+}
+
+// Terminates the session, releasing its memory.
+func (s *Sessions) Forget(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, has := s.sessions[id]; has {
+		delete(s.sessions, id)
+		release(e.memory)
+	}
+}
+
+// intentional the loss of memories of all sessions
+func (s *Sessions) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, e := range s.sessions {
+		delete(s.sessions, id)
+		release(e.memory)
+	}
+}
+
+// Commit new observation into memory of the default session.
+func (s *Sessions) Commit(e *thinker.Observation) {
+	s.lookup("").Commit(e)
+}
+
+// Builds the context window for LLM using memory of the default session.
+func (s *Sessions) Context(prompt chatter.Message) []chatter.Message {
+	return s.lookup("").Context(prompt)
+}
+
+func (s *Sessions) lookup(id string) thinker.Memory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock()
+	s.evictIdle(now)
+
+	e, has := s.sessions[id]
+	if !has {
+		e = &session{memory: s.factory(id)}
+		s.sessions[id] = e
+		s.evictLRU(id)
+	}
+	e.accessed = now
+
+	m := &sessionMemory{Memory: e.memory, owner: s, entry: e}
+	if inspector, ok := e.memory.(thinker.Inspector); ok {
+		return &sessionInspector{sessionMemory: m, Inspector: inspector}
+	}
+	return m
+}
+
+// refreshes the access time of the session, counting runs using it
+func (s *Sessions) touch(e *session, runs int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.accessed = s.clock()
+	e.runs += runs
+}
+
+func (s *Sessions) evictIdle(now time.Time) {
+	if s.idle <= 0 {
+		return
+	}
+
+	for id, e := range s.sessions {
+		if e.runs == 0 && now.Sub(e.accessed) > s.idle {
+			delete(s.sessions, id)
+			release(e.memory)
+		}
+	}
+}
+
+// evicts the least recently used sessions but the given one and sessions
+// in use, the capacity is exceeded if all sessions are in use
+func (s *Sessions) evictLRU(keep string) {
+	for s.cap > 0 && len(s.sessions) > s.cap {
+		var (
+			lru   string
+			at    time.Time
+			found bool
+		)
+		for id, e := range s.sessions {
+			if id != keep && e.runs == 0 && (!found || e.accessed.Before(at)) {
+				lru, at, found = id, e.accessed, true
+			}
+		}
+		if !found {
+			return
+		}
+
+		e := s.sessions[lru]
+		delete(s.sessions, lru)
+		release(e.memory)
+	}
+}
+
+func release(memory thinker.Memory) {
+	if c, ok := memory.(io.Closer); ok {
+		c.Close()
+	}
+}
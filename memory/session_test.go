@@ -0,0 +1,166 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+func TestSessions(t *testing.T) {
+	factory := func(id string) thinker.Memory { return NewStream(INFINITE, chatter.Stratum(id)) }
+
+	t.Run("Isolation", func(t *testing.T) {
+		s := NewSessions(0, 0, factory)
+		a := WithSession(context.Background(), "a")
+		b := WithSession(context.Background(), "b")
+		observe(s.Session(a), "x")
+		observe(s.Session(b), "y")
+
+		it.Then(t).Should(
+			it.Equal(SessionOf(a), "a"),
+			it.Equal(s.Len(), 2),
+			it.Seq(windowOf(s.Session(a), nil)).Equal("a", "x?", "x."),
+			it.Seq(windowOf(s.Session(b), nil)).Equal("b", "y?", "y."),
+		)
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		s := NewSessions(0, 0, factory)
+		observe(s, "x")
+
+		it.Then(t).Should(
+			it.Equal(SessionOf(context.Background()), ""),
+			it.Seq(windowOf(s.Session(context.Background()), nil)).Equal("x?", "x."),
+		)
+	})
+
+	t.Run("Idle", func(t *testing.T) {
+		now := time.Now()
+		s := NewSessions(0, time.Minute, factory)
+		s.clock = func() time.Time { return now }
+
+		a := WithSession(context.Background(), "a")
+		b := WithSession(context.Background(), "b")
+		observe(s.Session(a), "x")
+		now = now.Add(2 * time.Minute)
+		s.Session(b)
+
+		it.Then(t).Should(
+			it.Equal(s.Len(), 1),
+			it.Seq(windowOf(s.Session(a), nil)).Equal("a"),
+		)
+	})
+
+	t.Run("IdleRefresh", func(t *testing.T) {
+		now := time.Now()
+		s := NewSessions(0, time.Minute, factory)
+		s.clock = func() time.Time { return now }
+
+		a := WithSession(context.Background(), "a")
+		b := WithSession(context.Background(), "b")
+		m := s.Session(a)
+		now = now.Add(50 * time.Second)
+		observe(m, "x")
+		now = now.Add(50 * time.Second)
+		s.Session(b)
+
+		it.Then(t).Should(
+			it.Equal(s.Len(), 2),
+			it.Seq(windowOf(s.Session(a), nil)).Equal("a", "x?", "x."),
+		)
+	})
+
+	t.Run("InUse", func(t *testing.T) {
+		now := time.Now()
+		s := NewSessions(1, time.Minute, factory)
+		s.clock = func() time.Time { return now }
+
+		a := WithSession(context.Background(), "a")
+		b := WithSession(context.Background(), "b")
+		m := s.Session(a)
+		thinker.OnStart(a, m)
+		now = now.Add(2 * time.Minute)
+		s.Session(b)
+
+		it.Then(t).Should(
+			it.Equal(s.Len(), 2),
+		)
+
+		observe(m, "x")
+		thinker.OnEnd(a, m)
+		now = now.Add(2 * time.Minute)
+		s.Session(b)
+
+		it.Then(t).Should(
+			it.Equal(s.Len(), 1),
+			it.Seq(windowOf(s.Session(a), nil)).Equal("a"),
+		)
+	})
+
+	t.Run("Capacity", func(t *testing.T) {
+		now := time.Now()
+		s := NewSessions(2, 0, factory)
+		s.clock = func() time.Time { now = now.Add(time.Second); return now }
+
+		a := WithSession(context.Background(), "a")
+		b := WithSession(context.Background(), "b")
+		c := WithSession(context.Background(), "c")
+		observe(s.Session(a), "x")
+		observe(s.Session(b), "y")
+		s.Session(a)
+		s.Session(c)
+
+		it.Then(t).Should(
+			it.Equal(s.Len(), 2),
+			it.Seq(windowOf(s.Session(a), nil)).Equal("a", "x?", "x."),
+			it.Seq(windowOf(s.Session(b), nil)).Equal("b"),
+		)
+	})
+
+	t.Run("Forget", func(t *testing.T) {
+		s := NewSessions(0, 0, factory)
+		a := WithSession(context.Background(), "a")
+		observe(s.Session(a), "x")
+		s.Forget("a")
+
+		it.Then(t).Should(
+			it.Equal(s.Len(), 0),
+		)
+	})
+
+	t.Run("Inspector", func(t *testing.T) {
+		s := NewSessions(0, 0, factory)
+		a := WithSession(context.Background(), "a")
+		x := observe(s.Session(a), "x")
+		observe(s.Session(a), "y")
+
+		inspector, ok := s.Session(a).(thinker.Inspector)
+		it.Then(t).Must(it.True(ok))
+
+		inspector.Forget(x.Created)
+		it.Then(t).Should(
+			it.Equal(inspector.Len(), 1),
+			it.Seq(windowOf(s.Session(a), nil)).Equal("a", "y?", "y."),
+		)
+	})
+
+	t.Run("NoInspector", func(t *testing.T) {
+		s := NewSessions(0, 0, func(string) thinker.Memory { return NewVoid("") })
+		_, ok := s.Session(context.Background()).(thinker.Inspector)
+		it.Then(t).Should(
+			it.True(!ok),
+		)
+	})
+}
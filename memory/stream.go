@@ -9,8 +9,6 @@
 package memory
 
 import (
-	"sync"
-
 	"github.com/fogfish/guid/v2"
 	"github.com/kshard/chatter"
 	"github.com/kshard/thinker"
@@ -20,31 +18,43 @@ const INFINITE = -1
 
 // The stream memory retains all of the agent's observations in the time ordered sequence.
 type Stream struct {
-	mu      sync.Mutex
-	heap    map[guid.K]*thinker.Observation
-	commits []guid.K
+	archive
 	stratum chatter.Stratum
 	cap     int
+	budget  int
 }
 
-var _ thinker.Memory = (*Stream)(nil)
+var (
+	_ thinker.Memory    = (*Stream)(nil)
+	_ thinker.Inspector = (*Stream)(nil)
+)
 
 // Creates new stream memory that retains all of the agent's observations.
 func NewStream(cap int, stratum chatter.Stratum) *Stream {
 	return &Stream{
-		heap:    make(map[guid.K]*thinker.Observation),
-		commits: make([]guid.K, 0),
+		archive: newArchive(),
 		stratum: stratum,
 		cap:     cap,
 	}
 }
 
+// Limits the context window by the token budget. The stratum and the prompt
+// are always retained, the oldest observations are dropped first.
+// The Approx estimator is used if estimator is nil.
+func (s *Stream) WithBudget(budget int, estimator Estimator) *Stream {
+	if estimator == nil {
+		estimator = Approx
+	}
+
+	s.budget, s.estimator = budget, estimator
+	return s
+}
+
 func (s *Stream) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.heap = make(map[guid.K]*thinker.Observation)
-	s.commits = make([]guid.K, 0)
+	s.reset()
 }
 
 // Commit new observation into memory.
@@ -52,18 +62,24 @@ func (s *Stream) Commit(e *thinker.Observation) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.heap[e.Created] = e
-	s.commits = append(s.commits, e.Created)
+	s.append(e)
 
 	if s.cap > 0 && len(s.commits) > s.cap {
-		head := s.commits[0]
-		s.commits = s.commits[1:]
-		delete(s.heap, head)
+		// the tool invocation and its result are evicted together,
+		// the memory exceeds the capacity only if the recent unit does not fit
+		at := align(s.heap, s.commits, len(s.commits)-s.cap)
+		for _, id := range s.commits[:at] {
+			delete(s.heap, id)
+		}
+		s.commits = s.commits[at:]
 	}
 }
 
 // Builds the context window for LLM using incoming prompt.
 func (s *Stream) Context(prompt chatter.Message) []chatter.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	seq := make([]chatter.Message, 0)
 	if len(s.stratum) > 0 {
 		seq = append(seq, s.stratum)
@@ -80,5 +96,5 @@ func (s *Stream) Context(prompt chatter.Message) []chatter.Message {
 		seq = append(seq, prompt)
 	}
 
-	return seq
+	return window(s.budget, s.estimator, seq, prompt != nil)
 }
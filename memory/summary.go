@@ -0,0 +1,211 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fogfish/guid/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// The summary memory retains recent observations verbatim and compacts
+// the older ones into the running summary using LLM. The context window is
+//
+//	[stratum, summary, query₁, reply₁, …, prompt]
+type Summary struct {
+	archive
+	llm       chatter.Chatter
+	stratum   chatter.Stratum
+	summary   string
+	folded    map[guid.K]struct{}
+	threshold int
+	recent    int
+}
+
+var (
+	_ thinker.Memory    = (*Summary)(nil)
+	_ thinker.Inspector = (*Summary)(nil)
+)
+
+// Creates new summary memory. The oldest observations are folded into
+// the summary once history grows past the threshold, the recent observations
+// are kept verbatim.
+func NewSummary(llm chatter.Chatter, threshold int, recent int, stratum chatter.Stratum) *Summary {
+	return &Summary{
+		llm:       llm,
+		archive:   newArchive(),
+		stratum:   stratum,
+		folded:    make(map[guid.K]struct{}),
+		threshold: threshold,
+		recent:    min(recent, threshold),
+	}
+}
+
+// intentional the loss of memories, including facts, information and experiences
+func (s *Summary) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reset()
+	s.summary = ""
+	s.folded = make(map[guid.K]struct{})
+}
+
+// Commit new observation into memory. The commit blocks while LLM compacts
+// the history. The history is kept verbatim if LLM fails, the compaction
+// is retried on the next commit.
+func (s *Summary) Commit(e *thinker.Observation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.append(e)
+
+	if len(s.commits) <= s.threshold {
+		return
+	}
+
+	fold := s.commits[:align(s.heap, s.commits, len(s.commits)-s.recent)]
+	if len(fold) == 0 {
+		return
+	}
+
+	summary, err := s.compact(fold)
+	if err != nil {
+		return
+	}
+
+	for _, id := range fold {
+		delete(s.heap, id)
+		s.folded[id] = struct{}{}
+	}
+	s.commits = s.commits[len(fold):]
+	s.summary = summary
+}
+
+// Forget retained observations, the tool invocation and its result are
+// forgotten together. The summary cannot be rebuilt without the folded
+// observations, it is dropped if any of forgotten observations is folded
+// into it.
+func (s *Summary) Forget(ids ...guid.K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if _, has := s.folded[id]; has {
+			s.summary = ""
+			s.folded = make(map[guid.K]struct{})
+			break
+		}
+	}
+
+	s.commits = forget(s.heap, s.commits, ids)
+}
+
+// Builds the context window for LLM using incoming prompt.
+func (s *Summary) Context(prompt chatter.Message) []chatter.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seq := make([]chatter.Message, 0)
+	if len(s.stratum) > 0 {
+		seq = append(seq, s.stratum)
+	}
+
+	if len(s.summary) > 0 {
+		var note chatter.Prompt
+		note.WithBlob("Summary of the earlier conversation", s.summary)
+		seq = append(seq, &note)
+	}
+
+	for _, id := range s.commits {
+		evidence := s.heap[id]
+		evidence.Accessed = guid.G(guid.Clock)
+
+		seq = append(seq, evidence.Query.Content)
+		seq = append(seq, evidence.Reply.Content)
+	}
+
+	if prompt != nil {
+		seq = append(seq, prompt)
+	}
+
+	return seq
+}
+
+// folds observations into the running summary
+func (s *Summary) compact(fold []guid.K) (string, error) {
+	var sb strings.Builder
+	for _, id := range fold {
+		evidence := s.heap[id]
+		sb.WriteString("User: ")
+		sb.WriteString(transcript(evidence.Query.Content))
+		sb.WriteString("\nAssistant: ")
+		sb.WriteString(transcript(evidence.Reply.Content))
+		sb.WriteString("\n")
+	}
+
+	var prompt chatter.Prompt
+	prompt.WithTask("Summarize the conversation between user and assistant.")
+	prompt.WithRules(
+		"Strictly follow the rules",
+		"Preserve facts, decisions, tool results and open questions required to continue the conversation.",
+		"Merge the existing summary with the conversation, do not drop facts from the existing summary.",
+		"Reply with the summary only.",
+	)
+	if len(s.summary) > 0 {
+		prompt.WithBlob("Existing summary", s.summary)
+	}
+	prompt.WithBlob("Conversation", sb.String())
+
+	reply, err := s.llm.Prompt(context.Background(), []chatter.Message{&prompt})
+	if err != nil {
+		return "", err
+	}
+
+	return reply.String(), nil
+}
+
+// renders message as text, including tool invocations and answers
+func transcript(msg chatter.Message) string {
+	switch v := msg.(type) {
+	case *chatter.Reply:
+		seq := make([]string, 0, len(v.Content))
+		for _, c := range v.Content {
+			switch x := c.(type) {
+			case chatter.Invoke:
+				seq = append(seq, fmt.Sprintf("invoke %s(%s)", x.Cmd, x.Args.Value))
+			case chatter.Text:
+				seq = append(seq, string(x))
+			}
+		}
+		return strings.Join(seq, "\n")
+	case *chatter.Answer:
+		seq := make([]string, 0, len(v.Yield))
+		for _, x := range v.Yield {
+			seq = append(seq, fmt.Sprintf("%s returned %s", x.Source, x.Value))
+		}
+		return strings.Join(seq, "\n")
+	default:
+		return msg.String()
+	}
+}
+
+// Estimated number of tokens used by retained observations and the summary.
+func (s *Summary) Tokens() int {
+	n := s.archive.Tokens()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return n + Approx(chatter.Text(s.summary))
+}
@@ -0,0 +1,149 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+)
+
+// summarizer echoes the conversation topics and records the prompts
+type summarizer struct {
+	prompts []string
+	err     error
+}
+
+func (m *summarizer) Usage() chatter.Usage { return chatter.Usage{} }
+
+func (m *summarizer) Prompt(_ context.Context, seq []chatter.Message, _ ...chatter.Opt) (*chatter.Reply, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	m.prompts = append(m.prompts, seq[0].String())
+
+	topics := make([]string, 0)
+	for _, line := range strings.Split(seq[0].String(), "\n") {
+		if after, ok := strings.CutPrefix(line, "Assistant: "); ok {
+			topics = append(topics, after)
+		}
+	}
+
+	return &chatter.Reply{
+		Stage:   chatter.LLM_RETURN,
+		Content: []chatter.Content{chatter.Text(strings.Join(topics, " "))},
+	}, nil
+}
+
+func TestSummary(t *testing.T) {
+	t.Run("BelowThreshold", func(t *testing.T) {
+		llm := &summarizer{}
+		s := NewSummary(llm, 3, 1, "role.")
+		observe(s, "a")
+		observe(s, "b")
+		observe(s, "c")
+
+		it.Then(t).Should(
+			it.Equal(len(llm.prompts), 0),
+			it.Seq(windowOf(s, chatter.Text("d"))).Equal("role.", "a?", "a.", "b?", "b.", "c?", "c.", "d"),
+		)
+	})
+
+	t.Run("Compact", func(t *testing.T) {
+		llm := &summarizer{}
+		s := NewSummary(llm, 3, 1, "role.")
+		observe(s, "a")
+		observe(s, "b")
+		observe(s, "c")
+		observe(s, "d")
+
+		ctx := windowOf(s, chatter.Text("e"))
+		it.Then(t).Should(
+			it.Equal(len(llm.prompts), 1),
+			it.Equal(len(ctx), 5),
+			it.Equal(ctx[0], "role."),
+			it.String(ctx[1]).Contain("a. b. c."),
+			it.Seq(ctx[2:]).Equal("d?", "d.", "e"),
+		)
+	})
+
+	t.Run("CompactMergesSummary", func(t *testing.T) {
+		llm := &summarizer{}
+		s := NewSummary(llm, 2, 1, "")
+		observe(s, "a")
+		observe(s, "b")
+		observe(s, "c")
+		observe(s, "d")
+		observe(s, "e")
+
+		it.Then(t).Should(
+			it.Equal(len(llm.prompts), 2),
+			it.String(llm.prompts[1]).Contain("a. b."),
+			it.String(windowOf(s, nil)[0]).Contain("c. d."),
+			it.Seq(windowOf(s, nil)[1:]).Equal("e?", "e."),
+		)
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		llm := &summarizer{err: errors.New("llm error")}
+		s := NewSummary(llm, 1, 1, "")
+		observe(s, "a")
+		observe(s, "b")
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, nil)).Equal("a?", "a.", "b?", "b."),
+		)
+	})
+
+	t.Run("Forget", func(t *testing.T) {
+		llm := &summarizer{}
+		s := NewSummary(llm, 2, 1, "")
+		a := observe(s, "a")
+		observe(s, "b")
+		observe(s, "c")
+
+		s.Forget(a.Created)
+		ctx := windowOf(s, nil)
+		it.Then(t).Should(
+			it.Seq(ctx).Equal("c?", "c."),
+			it.Equal(s.Tokens(), Approx(chatter.Text("c?"))+Approx(chatter.Text("c."))),
+		)
+	})
+
+	t.Run("ForgetRetained", func(t *testing.T) {
+		llm := &summarizer{}
+		s := NewSummary(llm, 2, 1, "")
+		observe(s, "a")
+		observe(s, "b")
+		c := observe(s, "c")
+
+		s.Forget(c.Created)
+		it.Then(t).Should(
+			it.Equal(len(windowOf(s, nil)), 1),
+			it.String(windowOf(s, nil)[0]).Contain("a. b."),
+		)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		llm := &summarizer{}
+		s := NewSummary(llm, 1, 1, "role.")
+		observe(s, "a")
+		observe(s, "b")
+		s.Reset()
+
+		it.Then(t).Should(
+			it.Seq(windowOf(s, chatter.Text("c"))).Equal("role.", "c"),
+		)
+	})
+}
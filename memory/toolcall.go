@@ -0,0 +1,97 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"github.com/fogfish/guid/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// The tool invocation requested by LLM and the tool result are the atomic
+// unit of conversation, LLM providers reject the tool result without
+// the preceding invocation. The observation that answers to the tool
+// invocation is bound to the preceding observation, memories evict, fold
+// and recall observations by units.
+
+// true if the message is the result of tool invocation
+func isToolResult(msg chatter.Message) bool {
+	_, ok := msg.(*chatter.Answer)
+	return ok
+}
+
+// moves the index of commits to the beginning of the unit, the unit cut by
+// the index is dropped whole so that the retained commits do not exceed
+// the limit. The most recent unit is always retained whole.
+func align(heap map[guid.K]*thinker.Observation, commits []guid.K, at int) int {
+	if at <= 0 || at >= len(commits) {
+		return at
+	}
+
+	next := at
+	for next < len(commits) && isToolResult(heap[commits[next]].Query.Content) {
+		next++
+	}
+	if next < len(commits) {
+		return next
+	}
+
+	for at > 0 && isToolResult(heap[commits[at]].Query.Content) {
+		at--
+	}
+	return at
+}
+
+// expands the selected observations to whole units, preserving the commit
+// order. The most recent unit is selected if pending prompt is the tool result.
+// The oldest units are dropped while the expansion exceeds the limit, the most
+// recent unit is always retained. The negative limit disables the check.
+func expand(heap map[guid.K]*thinker.Observation, commits []guid.K, selected []guid.K, pending bool, limit int) []guid.K {
+	if len(selected) == len(commits) && (limit < 0 || len(commits) <= limit) {
+		return selected
+	}
+
+	mark := make(map[guid.K]struct{}, len(selected))
+	for _, id := range selected {
+		mark[id] = struct{}{}
+	}
+
+	// selected units as [i, j) ranges of commits
+	units := make([][2]int, 0)
+	size := 0
+	for i := 0; i < len(commits); {
+		j := i + 1
+		for j < len(commits) && isToolResult(heap[commits[j]].Query.Content) {
+			j++
+		}
+
+		hit := pending && j == len(commits)
+		for k := i; k < j && !hit; k++ {
+			_, hit = mark[commits[k]]
+		}
+
+		if hit {
+			units = append(units, [2]int{i, j})
+			size += j - i
+		}
+		i = j
+	}
+
+	for limit >= 0 && size > limit && len(units) > 1 {
+		size -= units[0][1] - units[0][0]
+		units = units[1:]
+	}
+
+	seq := make([]guid.K, 0, size)
+	for _, unit := range units {
+		seq = append(seq, commits[unit[0]:unit[1]]...)
+	}
+
+	return seq
+}
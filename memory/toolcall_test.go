@@ -0,0 +1,187 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package memory
+
+import (
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+func TestToolCall(t *testing.T) {
+	invoke := &chatter.Reply{
+		Stage: chatter.LLM_INVOKE,
+		Content: []chatter.Content{
+			chatter.Invoke{Cmd: "fs_read", Args: chatter.Json{ID: "1", Value: []byte(`{}`)}},
+		},
+	}
+	answer := &chatter.Answer{
+		Yield: []chatter.Json{{ID: "1", Source: "fs_read", Value: []byte(`{}`)}},
+	}
+
+	// plain question and answer
+	ask := func(m thinker.Memory, text string) {
+		m.Commit(
+			thinker.NewObservation(
+				&chatter.Prompt{Task: chatter.Task(text + "?")},
+				&chatter.Reply{Content: []chatter.Content{chatter.Text(text + ".")}},
+			),
+		)
+	}
+
+	// question answered using the tool
+	tool := func(m thinker.Memory, text string) {
+		m.Commit(thinker.NewObservation(&chatter.Prompt{Task: chatter.Task(text + "?")}, invoke))
+		m.Commit(
+			thinker.NewObservation(
+				answer,
+				&chatter.Reply{Content: []chatter.Content{chatter.Text(text + ".")}},
+			),
+		)
+	}
+
+	// conversation layout: Prompt, Reply, Invoke, Answer
+	layout := func(seq []chatter.Message) string {
+		s := ""
+		for _, msg := range seq {
+			switch v := msg.(type) {
+			case *chatter.Reply:
+				if v.Stage == chatter.LLM_INVOKE {
+					s += "I"
+				} else {
+					s += "R"
+				}
+			case *chatter.Answer:
+				s += "A"
+			default:
+				s += "P"
+			}
+		}
+		return s
+	}
+
+	t.Run("StreamEvict", func(t *testing.T) {
+		s := NewStream(2, "")
+		ask(s, "a")
+		tool(s, "b")
+		ask(s, "c")
+
+		it.Then(t).Should(
+			it.Equal(layout(s.Context(nil)), "PR"),
+		)
+
+		ask(s, "d")
+		it.Then(t).Should(
+			it.Equal(layout(s.Context(nil)), "PRPR"),
+		)
+	})
+
+	t.Run("StreamEvictUnit", func(t *testing.T) {
+		s := NewStream(3, "")
+		ask(s, "a")
+		tool(s, "b")
+		ask(s, "c")
+
+		it.Then(t).Should(
+			it.Equal(layout(s.Context(nil)), "PIARPR"),
+		)
+
+		ask(s, "d")
+		it.Then(t).Should(
+			it.Equal(layout(s.Context(nil)), "PRPR"),
+		)
+	})
+
+	t.Run("StreamPending", func(t *testing.T) {
+		s := NewStream(1, "")
+		ask(s, "a")
+		s.Commit(thinker.NewObservation(&chatter.Prompt{Task: "b?"}, invoke))
+		s.Commit(thinker.NewObservation(answer, invoke))
+
+		it.Then(t).Should(
+			it.Equal(layout(s.Context(answer)), "PIAIA"),
+		)
+	})
+
+	t.Run("Budget", func(t *testing.T) {
+		s := NewStream(INFINITE, "").WithBudget(3, unit)
+		tool(s, "a")
+		ask(s, "b")
+
+		it.Then(t).Should(
+			it.Equal(layout(s.Context(&chatter.Prompt{Task: "c?"})), "PRP"),
+		)
+
+		s.Commit(thinker.NewObservation(&chatter.Prompt{Task: "c?"}, invoke))
+		it.Then(t).Should(
+			it.Equal(layout(s.Context(answer)), "PIA"),
+		)
+
+		s.Commit(thinker.NewObservation(answer, invoke))
+		it.Then(t).Should(
+			it.Equal(layout(s.Context(answer)), "PIA"),
+		)
+
+		s = s.WithBudget(5, unit)
+		it.Then(t).Should(
+			it.Equal(layout(s.Context(answer)), "PIAIA"),
+		)
+	})
+
+	t.Run("SummaryFold", func(t *testing.T) {
+		llm := &summarizer{}
+		s := NewSummary(llm, 2, 1, "")
+		ask(s, "a")
+		tool(s, "b")
+
+		it.Then(t).Should(
+			it.Equal(len(llm.prompts), 1),
+			it.Equal(layout(s.Context(nil)), "PPIAR"),
+		)
+	})
+
+	t.Run("Semantic", func(t *testing.T) {
+		s := NewSemantic(1, "", embedder{"cat", "dog"})
+		ask(s, "cat")
+		tool(s, "dog")
+
+		it.Then(t).Should(
+			it.Equal(layout(s.Context(chatter.Text("dog"))), "PIARP"),
+		)
+	})
+
+	t.Run("SemanticLimit", func(t *testing.T) {
+		s := NewSemantic(2, "", embedder{"cat", "dog", "cow"})
+		tool(s, "cat")
+		ask(s, "cow")
+		tool(s, "dog")
+
+		it.Then(t).Should(
+			it.Equal(layout(s.Context(chatter.Text("cat cow"))), "PRP"),
+		)
+	})
+
+	t.Run("Retrieval", func(t *testing.T) {
+		s := NewRetrieval(1, "", nil).WithRater(rater{}).
+			WithScoring(Scoring{Importance: 1.0, Decay: 0.995})
+		s.Commit(
+			thinker.NewObservation(
+				&chatter.Prompt{Task: "a?"},
+				&chatter.Reply{Content: []chatter.Content{chatter.Text("a!")}},
+			),
+		)
+		s.Commit(thinker.NewObservation(&chatter.Prompt{Task: "b?"}, invoke))
+
+		it.Then(t).Should(
+			it.Equal(layout(s.Context(answer)), "PIA"),
+		)
+	})
+}
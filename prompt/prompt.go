@@ -28,6 +28,14 @@ type Prompt struct {
 	// Default is 3
 	Retry int
 
+	// Optional field to limit the number of steps (LLM calls) of the tool-use loop.
+	// Default is 0, the number of steps is unbounded
+	Steps int
+
+	// Optional field to limit the number of identical tool calls in a row.
+	// Default is 0, the detection of repeated calls is disabled
+	Repeats int
+
 	// Optional debugging mode
 	Debug bool
 
@@ -57,6 +65,8 @@ type yamlPrompt struct {
 	Format  string       `yaml:"format,omitempty"`
 	RunsOn  string       `yaml:"runs-on,omitempty"`
 	Retry   int          `yaml:"retry,omitempty"`
+	Steps   int          `yaml:"steps,omitempty"`
+	Repeats int          `yaml:"repeats,omitempty"`
 	Debug   bool         `yaml:"debug,omitempty"`
 	Schema  *yamlSchema  `yaml:"schema,omitempty"`
 	Servers []yamlServer `yaml:"servers,omitempty"`
@@ -166,10 +176,12 @@ func toPrompt(raw *yamlPrompt, prompt string) *Prompt {
 	}
 
 	return &Prompt{
-		Prompt: prompt,
-		RunsOn: raw.RunsOn,
-		Retry:  raw.Retry,
-		Debug:  raw.Debug,
+		Prompt:  prompt,
+		RunsOn:  raw.RunsOn,
+		Retry:   raw.Retry,
+		Steps:   raw.Steps,
+		Repeats: raw.Repeats,
+		Debug:   raw.Debug,
 		Schema: Schema{
 			Format: raw.Format,
 			Input:  inputSchema,
@@ -89,6 +89,19 @@ func TestParseFrontmatterFormat(t *testing.T) {
 	)
 }
 
+func TestParseFrontmatterSteps(t *testing.T) {
+	const text = "---\nsteps: 12\nrepeats: 2\n---\nUse tools.\n"
+
+	p, err := prompt.Parse(strings.NewReader(text))
+
+	it.Then(t).Should(
+		it.Nil(err),
+		it.Equal(p.Steps, 12),
+		it.Equal(p.Repeats, 2),
+		it.Equal(p.Retry, 3),
+	)
+}
+
 func TestParseFrontmatterWithSchema(t *testing.T) {
 	const text = "---\nformat: json\nruns-on: small\nschema:\n  input:\n    type: object\n    properties:\n      country:\n        type: string\n  reply:\n    type: object\n    properties:\n      capital:\n        type: string\n---\nWhat is the capital of {{.Country}}?\n"
 
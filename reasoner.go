@@ -24,3 +24,21 @@ type Reasoner[B any] interface {
 	// Deduct new goal for the agent to pursue.
 	Deduct(State[B]) (Phase, chatter.Message, error)
 }
+
+// ForkReasoner is the reasoner with the state of the run (e.g. the current
+// state of the workflow). Agents fork the reasoner at the beginning of
+// every run, so that runs are isolated and the reasoner is safe to reuse.
+type ForkReasoner[B any] interface {
+	Reasoner[B]
+
+	// Creates the reasoner with the fresh state of the run.
+	Fork() Reasoner[B]
+}
+
+// Forks the reasoner if it has the state of the run.
+func ReasonerOf[B any](reasoner Reasoner[B]) Reasoner[B] {
+	if f, ok := reasoner.(ForkReasoner[B]); ok {
+		return f.Fork()
+	}
+	return reasoner
+}
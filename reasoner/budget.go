@@ -0,0 +1,225 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package reasoner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/ledger"
+)
+
+// Limits of the spend, zero value disables the limit.
+type Limits struct {
+	// Wall-clock time since the first LLM call or the first epoch
+	Duration time.Duration
+
+	// Cumulative input and output tokens
+	Tokens int
+
+	// Estimated cost in currency units
+	Cost float64
+}
+
+// Report of the spend.
+type Report struct {
+	Elapsed     time.Duration
+	InputTokens int
+	ReplyTokens int
+	Cost        float64
+}
+
+// Spend tracks elapsed time, tokens and cost of LLM calls. The usage is read
+// from the ledger of the spend and priced by its price table (see ledger).
+// Agents running within the context of the spend account every LLM call,
+// metered models account calls made outside of it. The spend is shared
+// across agents, so that the whole pipeline has one ceiling.
+type Spend struct {
+	mu      sync.Mutex
+	limits  Limits
+	ledger  *ledger.Ledger
+	base    ledger.Usage
+	started time.Time
+	clock   func() time.Time
+}
+
+// Creates new spend with the limits.
+func NewSpend(limits Limits) *Spend {
+	return &Spend{
+		limits: limits,
+		ledger: ledger.New(),
+		clock:  time.Now,
+	}
+}
+
+// Configures the price of the model at the ledger of the spend.
+func (s *Spend) WithPrice(model string, price ledger.Price) *Spend {
+	s.ledger.WithPrice(model, price)
+	return s
+}
+
+// Configures the ledger of the spend, e.g. the one used for reporting.
+// The usage accounted before is disregarded.
+func (s *Spend) WithLedger(l *ledger.Ledger) *Spend {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ledger = l
+	s.base = l.Report().Total
+	return s
+}
+
+// Binds the ledger of the spend to the context, every agent running within
+// the context accounts the usage of LLM calls to the spend.
+func (s *Spend) Context(ctx context.Context) context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ledger.Context(ctx)
+}
+
+// Meter the model, the call fails if any limit is reached. The usage of every
+// LLM call is accounted to the model unless the context is bound to the spend,
+// agents account it within the scope of the context then.
+func (s *Spend) Meter(model string, llm chatter.Chatter) chatter.Chatter {
+	return &meter{Chatter: llm, model: model, spend: s}
+}
+
+// Returns the spend report.
+func (s *Spend) Report() Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage := s.usage()
+	report := Report{
+		InputTokens: usage.InputTokens,
+		ReplyTokens: usage.ReplyTokens,
+		Cost:        usage.Cost,
+	}
+	if !s.started.IsZero() {
+		report.Elapsed = s.clock().Sub(s.started)
+	}
+	return report
+}
+
+// Resets the spend, the clock starts again from the next call. The usage
+// accounted to the ledger is retained, the spend disregards it.
+func (s *Spend) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.started = time.Time{}
+	s.base = s.ledger.Report().Total
+}
+
+// Check limits of the spend, the typed error is returned if any limit is reached.
+func (s *Spend) Check() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.check()
+}
+
+func (s *Spend) check() error {
+	now := s.clock()
+	if s.started.IsZero() {
+		s.started = now
+	}
+
+	if s.limits.Duration > 0 && now.Sub(s.started) >= s.limits.Duration {
+		return thinker.ErrMaxTime.With(thinker.ErrAborted, s.limits.Duration)
+	}
+
+	usage := s.usage()
+	if s.limits.Tokens > 0 && usage.Tokens() >= s.limits.Tokens {
+		return thinker.ErrMaxTokens.With(thinker.ErrAborted, s.limits.Tokens)
+	}
+
+	if s.limits.Cost > 0 && usage.Cost >= s.limits.Cost {
+		return thinker.ErrMaxCost.With(thinker.ErrAborted, s.limits.Cost)
+	}
+
+	return nil
+}
+
+// usage accounted since the reset
+func (s *Spend) usage() ledger.Usage {
+	total := s.ledger.Report().Total
+	return ledger.Usage{
+		InputTokens: total.InputTokens - s.base.InputTokens,
+		ReplyTokens: total.ReplyTokens - s.base.ReplyTokens,
+		Cost:        total.Cost - s.base.Cost,
+	}
+}
+
+func (s *Spend) account(ctx context.Context, model string, usage chatter.Usage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ledger.Of(ctx) == s.ledger {
+		return
+	}
+
+	scope := ledger.ScopeOf(ctx)
+	scope.Model = model
+	s.ledger.Account(scope, usage)
+}
+
+type meter struct {
+	chatter.Chatter
+	model string
+	spend *Spend
+}
+
+func (m *meter) Prompt(ctx context.Context, prompt []chatter.Message, opt ...chatter.Opt) (*chatter.Reply, error) {
+	if err := m.spend.Check(); err != nil {
+		return nil, err
+	}
+
+	reply, err := m.Chatter.Prompt(ctx, prompt, opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	m.spend.account(ctx, m.model, reply.Usage)
+	return reply, nil
+}
+
+//------------------------------------------------------------------------------
+
+// The budget reasoner aborts execution if any limit of the spend is reached.
+type Budget[B any] struct {
+	thinker.Reasoner[B]
+	spend *Spend
+}
+
+// Creates new budget reasoner that limits the spend of agents.
+func NewBudget[B any](spend *Spend, reasoner thinker.Reasoner[B]) Budget[B] {
+	return Budget[B]{Reasoner: reasoner, spend: spend}
+}
+
+func (budget Budget[B]) Fork() thinker.Reasoner[B] {
+	return Budget[B]{Reasoner: thinker.ReasonerOf(budget.Reasoner), spend: budget.spend}
+}
+
+func (budget Budget[B]) OnStart(ctx context.Context)            { onStart(ctx, budget.Reasoner) }
+func (budget Budget[B]) OnEnd(ctx context.Context)              { onEnd(ctx, budget.Reasoner) }
+func (budget Budget[B]) OnAbort(ctx context.Context, err error) { onAbort(ctx, err, budget.Reasoner) }
+
+// Deduct new goal for the agent to pursue.
+func (budget Budget[B]) Deduct(state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
+	if err := budget.spend.Check(); err != nil {
+		return thinker.AGENT_ABORT, nil, err
+	}
+
+	return budget.Reasoner.Deduct(state)
+}
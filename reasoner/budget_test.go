@@ -0,0 +1,169 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package reasoner
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/ledger"
+)
+
+// replies with the fixed usage
+type usage chatter.Usage
+
+func (u usage) Usage() chatter.Usage { return chatter.Usage(u) }
+
+func (u usage) Prompt(context.Context, []chatter.Message, ...chatter.Opt) (*chatter.Reply, error) {
+	return &chatter.Reply{Usage: chatter.Usage(u)}, nil
+}
+
+func TestBudget(t *testing.T) {
+	call := func(llm chatter.Chatter) error {
+		_, err := llm.Prompt(context.Background(), []chatter.Message{chatter.Text("a")})
+		return err
+	}
+
+	t.Run("Tokens", func(t *testing.T) {
+		spend := NewSpend(Limits{Tokens: 100})
+		llm := spend.Meter("base", usage{InputTokens: 30, ReplyTokens: 20})
+		r := NewBudget(spend, NewVoid[string]())
+
+		it.Then(t).Must(it.Nil(call(llm)))
+		phase, _, err := r.Deduct(thinker.State[string]{})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_RETURN),
+		)
+
+		it.Then(t).Must(it.Nil(call(llm)))
+		phase, _, err = r.Deduct(thinker.State[string]{})
+		it.Then(t).Should(
+			it.Equal(phase, thinker.AGENT_ABORT),
+			it.True(errors.Is(err, thinker.ErrAborted)),
+			it.String(err.Error()).Contain("max tokens 100"),
+		)
+
+		it.Then(t).ShouldNot(it.Nil(call(llm)))
+	})
+
+	t.Run("Cost", func(t *testing.T) {
+		spend := NewSpend(Limits{Cost: 0.3}).
+			WithPrice("cheap", ledger.Price{Input: 1.0, Output: 2.0}).
+			WithPrice("strong", ledger.Price{Input: 10.0, Output: 20.0})
+		cheap := spend.Meter("cheap", usage{InputTokens: 100_000, ReplyTokens: 10_000})
+		strong := spend.Meter("strong", usage{InputTokens: 10_000, ReplyTokens: 1_000})
+
+		it.Then(t).Must(
+			it.Nil(call(cheap)),
+			it.Nil(call(strong)),
+		)
+		it.Then(t).Should(
+			it.True(math.Abs(spend.Report().Cost-0.24) < 1e-9),
+			it.Nil(spend.Check()),
+		)
+
+		it.Then(t).Must(it.Nil(call(cheap)))
+		it.Then(t).Should(
+			it.String(spend.Check().Error()).Contain("max cost"),
+		)
+	})
+
+	t.Run("Duration", func(t *testing.T) {
+		now := time.Now()
+		spend := NewSpend(Limits{Duration: time.Minute})
+		spend.clock = func() time.Time { return now }
+		r := NewBudget(spend, NewVoid[string]())
+
+		_, _, err := r.Deduct(thinker.State[string]{})
+		it.Then(t).Should(it.Nil(err))
+
+		now = now.Add(2 * time.Minute)
+		_, _, err = r.Deduct(thinker.State[string]{})
+		it.Then(t).Should(
+			it.String(err.Error()).Contain("max time 1m0s"),
+			it.Equal(spend.Report().Elapsed, 2*time.Minute),
+		)
+
+		spend.Reset()
+		_, _, err = r.Deduct(thinker.State[string]{})
+		it.Then(t).Should(it.Nil(err))
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		spend := NewSpend(Limits{Tokens: 100, Cost: 1.0}).
+			WithPrice("base", ledger.Price{Input: 10.0, Output: 20.0})
+		ctx := ledger.WithModel(spend.Context(context.Background()), "base")
+		r := NewBudget(spend, NewVoid[string]())
+
+		ledger.Account(ctx, chatter.Usage{InputTokens: 30, ReplyTokens: 20})
+		_, _, err := r.Deduct(thinker.State[string]{})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.True(math.Abs(spend.Report().Cost-0.0007) < 1e-9),
+		)
+
+		ledger.Account(ctx, chatter.Usage{InputTokens: 30, ReplyTokens: 20})
+		phase, _, err := r.Deduct(thinker.State[string]{})
+		it.Then(t).Should(
+			it.Equal(phase, thinker.AGENT_ABORT),
+			it.String(err.Error()).Contain("max tokens 100"),
+		)
+	})
+
+	t.Run("MeterWithinContext", func(t *testing.T) {
+		spend := NewSpend(Limits{Tokens: 100})
+		ctx := spend.Context(context.Background())
+		llm := spend.Meter("base", usage{InputTokens: 30, ReplyTokens: 20})
+
+		reply, err := llm.Prompt(ctx, []chatter.Message{chatter.Text("a")})
+		it.Then(t).Must(it.Nil(err))
+		ledger.Account(ctx, reply.Usage)
+
+		it.Then(t).Should(
+			it.Equal(spend.Report().InputTokens, 30),
+			it.Equal(spend.Report().ReplyTokens, 20),
+		)
+	})
+
+	t.Run("Ledger", func(t *testing.T) {
+		l := ledger.New().WithPrice("base", ledger.Price{Input: 10.0})
+		l.Account(ledger.Scope{Model: "base"}, chatter.Usage{InputTokens: 1000})
+
+		spend := NewSpend(Limits{}).WithLedger(l)
+		llm := spend.Meter("base", usage{InputTokens: 100})
+		it.Then(t).Must(it.Nil(call(llm)))
+
+		it.Then(t).Should(
+			it.Equal(spend.Report().InputTokens, 100),
+			it.True(math.Abs(spend.Report().Cost-0.001) < 1e-9),
+			it.Equal(l.Report().Total.InputTokens, 1100),
+		)
+	})
+
+	t.Run("Shared", func(t *testing.T) {
+		spend := NewSpend(Limits{Tokens: 10})
+		a := spend.Meter("a", usage{InputTokens: 5})
+		b := spend.Meter("b", usage{InputTokens: 5})
+
+		it.Then(t).Must(
+			it.Nil(call(a)),
+			it.Nil(call(b)),
+		)
+		it.Then(t).ShouldNot(
+			it.Nil(call(a)),
+		)
+	})
+}
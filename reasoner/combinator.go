@@ -0,0 +1,173 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package reasoner
+
+import (
+	"context"
+
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// Combinators build the goal logic from simple reasoners. The AGENT_ASK
+// phase without prompt is the neutral decision, the reasoner has no opinion
+// about the state and defers the decision to others. Purge is propagated
+// to all components.
+
+// Seq runs reasoners in order and takes the first decision other than
+// AGENT_ASK. The agent is aborted with thinker.ErrDeadEnd if no reasoner has
+// the opinion, end the sequence with the reasoner that always decides (e.g.
+// Void). The nested sequence is flattened, it defers to the enclosing one.
+// The empty sequence returns results.
+func Seq[B any](seq ...thinker.Reasoner[B]) thinker.Reasoner[B] {
+	flat := make(seqReasoner[B], 0, len(seq))
+	for _, r := range seq {
+		if inner, ok := r.(seqReasoner[B]); ok && len(inner) > 0 {
+			flat = append(flat, inner...)
+			continue
+		}
+		flat = append(flat, r)
+	}
+
+	return flat
+}
+
+type seqReasoner[B any] []thinker.Reasoner[B]
+
+func (seq seqReasoner[B]) Purge() {
+	for _, r := range seq {
+		r.Purge()
+	}
+}
+
+func (seq seqReasoner[B]) Fork() thinker.Reasoner[B] { return seqReasoner[B](fork(seq)) }
+
+func (seq seqReasoner[B]) OnStart(ctx context.Context)            { onStart(ctx, seq...) }
+func (seq seqReasoner[B]) OnEnd(ctx context.Context)              { onEnd(ctx, seq...) }
+func (seq seqReasoner[B]) OnAbort(ctx context.Context, err error) { onAbort(ctx, err, seq...) }
+
+// Deduct new goal for the agent to pursue.
+func (seq seqReasoner[B]) Deduct(state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
+	if len(seq) == 0 {
+		return thinker.AGENT_RETURN, nil, nil
+	}
+
+	for _, r := range seq {
+		phase, msg, err := r.Deduct(state)
+		if phase != thinker.AGENT_ASK || msg != nil || err != nil {
+			return phase, msg, err
+		}
+	}
+
+	return thinker.AGENT_ABORT, nil, thinker.ErrDeadEnd
+}
+
+//------------------------------------------------------------------------------
+
+// Guard aborts the agent if predicate fails, otherwise it has no opinion.
+//
+//	reasoner.Guard(func(s thinker.State[B]) error {
+//		if s.Confidence < 0.1 { return errors.New("hallucination") }
+//		return nil
+//	})
+func Guard[B any](f func(thinker.State[B]) error) thinker.Reasoner[B] {
+	return guardReasoner[B](f)
+}
+
+type guardReasoner[B any] func(thinker.State[B]) error
+
+func (f guardReasoner[B]) Purge() {}
+
+// Deduct new goal for the agent to pursue.
+func (f guardReasoner[B]) Deduct(state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
+	if err := f(state); err != nil {
+		return thinker.AGENT_ABORT, nil, thinker.ErrAborted.With(err)
+	}
+
+	return thinker.AGENT_ASK, nil, nil
+}
+
+//------------------------------------------------------------------------------
+
+// OnPhase applies the reasoner if the agent is at the phase, otherwise it has
+// no opinion. Use Seq to route multiple phases.
+//
+//	reasoner.Seq(
+//		reasoner.OnPhase(thinker.AGENT_RETRY, retry),
+//		reasoner.OnPhase(thinker.AGENT_REFINE, refine),
+//		reasoner.NewVoid[B](),
+//	)
+func OnPhase[B any](phase thinker.Phase, reasoner thinker.Reasoner[B]) thinker.Reasoner[B] {
+	return onPhaseReasoner[B]{phase: phase, reasoner: reasoner}
+}
+
+type onPhaseReasoner[B any] struct {
+	phase    thinker.Phase
+	reasoner thinker.Reasoner[B]
+}
+
+func (r onPhaseReasoner[B]) Purge() { r.reasoner.Purge() }
+
+func (r onPhaseReasoner[B]) Fork() thinker.Reasoner[B] {
+	return onPhaseReasoner[B]{phase: r.phase, reasoner: thinker.ReasonerOf(r.reasoner)}
+}
+
+func (r onPhaseReasoner[B]) OnStart(ctx context.Context)            { onStart(ctx, r.reasoner) }
+func (r onPhaseReasoner[B]) OnEnd(ctx context.Context)              { onEnd(ctx, r.reasoner) }
+func (r onPhaseReasoner[B]) OnAbort(ctx context.Context, err error) { onAbort(ctx, err, r.reasoner) }
+
+// Deduct new goal for the agent to pursue.
+func (r onPhaseReasoner[B]) Deduct(state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
+	if state.Phase != r.phase {
+		return thinker.AGENT_ASK, nil, nil
+	}
+
+	return r.reasoner.Deduct(state)
+}
+
+//------------------------------------------------------------------------------
+
+// Fallback runs reasoners in order until one of them does not fail.
+// The reasoner fails if it returns error or aborts the agent. The last
+// decision is returned if all reasoners fail.
+func Fallback[B any](seq ...thinker.Reasoner[B]) thinker.Reasoner[B] {
+	return fallbackReasoner[B](seq)
+}
+
+type fallbackReasoner[B any] []thinker.Reasoner[B]
+
+func (seq fallbackReasoner[B]) Purge() {
+	for _, r := range seq {
+		r.Purge()
+	}
+}
+
+func (seq fallbackReasoner[B]) Fork() thinker.Reasoner[B] { return fallbackReasoner[B](fork(seq)) }
+
+func (seq fallbackReasoner[B]) OnStart(ctx context.Context)            { onStart(ctx, seq...) }
+func (seq fallbackReasoner[B]) OnEnd(ctx context.Context)              { onEnd(ctx, seq...) }
+func (seq fallbackReasoner[B]) OnAbort(ctx context.Context, err error) { onAbort(ctx, err, seq...) }
+
+// Deduct new goal for the agent to pursue.
+func (seq fallbackReasoner[B]) Deduct(state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
+	var (
+		phase thinker.Phase = thinker.AGENT_ABORT
+		msg   chatter.Message
+		err   error = thinker.ErrAborted
+	)
+
+	for _, r := range seq {
+		phase, msg, err = r.Deduct(state)
+		if err == nil && phase != thinker.AGENT_ABORT {
+			return phase, msg, nil
+		}
+	}
+
+	return phase, msg, err
+}
@@ -0,0 +1,166 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package reasoner_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/reasoner"
+)
+
+// reasoner with the fixed decision, it counts purges
+type fixed struct {
+	phase  thinker.Phase
+	err    error
+	purged int
+}
+
+func (f *fixed) Purge() { f.purged++ }
+
+func (f *fixed) Deduct(thinker.State[string]) (thinker.Phase, chatter.Message, error) {
+	return f.phase, nil, f.err
+}
+
+func TestSeq(t *testing.T) {
+	t.Run("FirstOpinion", func(t *testing.T) {
+		a := &fixed{phase: thinker.AGENT_ASK}
+		b := &fixed{phase: thinker.AGENT_REFINE}
+		c := &fixed{phase: thinker.AGENT_RETURN}
+
+		phase, _, err := reasoner.Seq(a, b, c).Deduct(thinker.State[string]{})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_REFINE),
+		)
+	})
+
+	t.Run("NoOpinion", func(t *testing.T) {
+		phase, msg, err := reasoner.Seq[string](&fixed{}, &fixed{}).Deduct(thinker.State[string]{})
+		it.Then(t).Should(
+			it.True(errors.Is(err, thinker.ErrDeadEnd)),
+			it.Equal(phase, thinker.AGENT_ABORT),
+			it.Nil(msg),
+		)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		phase, _, err := reasoner.Seq(
+			reasoner.Seq[string](&fixed{}, &fixed{}),
+			&fixed{phase: thinker.AGENT_RETURN},
+		).Deduct(thinker.State[string]{})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_RETURN),
+		)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		phase, _, err := reasoner.Seq[string]().Deduct(thinker.State[string]{})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_RETURN),
+		)
+	})
+}
+
+func TestGuard(t *testing.T) {
+	guard := reasoner.Guard(func(s thinker.State[string]) error {
+		if s.Confidence < 0.1 {
+			return errors.New("hallucination")
+		}
+		return nil
+	})
+
+	t.Run("Pass", func(t *testing.T) {
+		phase, _, err := reasoner.Seq(guard, reasoner.NewVoid[string]()).
+			Deduct(thinker.State[string]{Confidence: 0.5})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_RETURN),
+		)
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		phase, _, err := reasoner.Seq(guard, reasoner.NewVoid[string]()).
+			Deduct(thinker.State[string]{Confidence: 0.0})
+		it.Then(t).Should(
+			it.Equal(phase, thinker.AGENT_ABORT),
+			it.True(errors.Is(err, thinker.ErrAborted)),
+			it.String(err.Error()).Contain("hallucination"),
+		)
+	})
+}
+
+func TestOnPhase(t *testing.T) {
+	r := reasoner.Seq(
+		reasoner.OnPhase[string](thinker.AGENT_RETRY, &fixed{phase: thinker.AGENT_ABORT}),
+		reasoner.OnPhase[string](thinker.AGENT_REFINE, &fixed{phase: thinker.AGENT_REFINE}),
+		reasoner.NewVoid[string](),
+	)
+
+	for phase, expect := range map[thinker.Phase]thinker.Phase{
+		thinker.AGENT_ASK:    thinker.AGENT_RETURN,
+		thinker.AGENT_RETRY:  thinker.AGENT_ABORT,
+		thinker.AGENT_REFINE: thinker.AGENT_REFINE,
+	} {
+		actual, _, _ := r.Deduct(thinker.State[string]{Phase: phase})
+		it.Then(t).Should(
+			it.Equal(actual, expect),
+		)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	t.Run("Recover", func(t *testing.T) {
+		phase, _, err := reasoner.Fallback[string](
+			&fixed{phase: thinker.AGENT_ABORT},
+			&fixed{phase: thinker.AGENT_REFINE, err: errors.New("failed")},
+			&fixed{phase: thinker.AGENT_RETURN},
+		).Deduct(thinker.State[string]{})
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_RETURN),
+		)
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		phase, _, err := reasoner.Fallback[string](
+			&fixed{phase: thinker.AGENT_ABORT},
+			&fixed{phase: thinker.AGENT_ABORT, err: errors.New("failed")},
+		).Deduct(thinker.State[string]{})
+
+		it.Then(t).Should(
+			it.Equal(phase, thinker.AGENT_ABORT),
+			it.String(err.Error()).Contain("failed"),
+		)
+	})
+}
+
+func TestPurge(t *testing.T) {
+	a, b, c, d := &fixed{}, &fixed{}, &fixed{}, &fixed{}
+	reasoner.NewEpoch(3,
+		reasoner.Seq(
+			a,
+			reasoner.OnPhase[string](thinker.AGENT_REFINE, b),
+			reasoner.Fallback[string](c, d),
+		),
+	).Purge()
+
+	it.Then(t).Should(
+		it.Equal(a.purged, 1),
+		it.Equal(b.purged, 1),
+		it.Equal(c.purged, 1),
+		it.Equal(d.purged, 1),
+	)
+}
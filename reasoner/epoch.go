@@ -9,6 +9,8 @@
 package reasoner
 
 import (
+	"context"
+
 	"github.com/kshard/chatter"
 	"github.com/kshard/thinker"
 )
@@ -25,6 +27,14 @@ func NewEpoch[B any](max int, reasoner thinker.Reasoner[B]) Epoch[B] {
 	return Epoch[B]{Reasoner: reasoner, max: max}
 }
 
+func (epoch Epoch[B]) Fork() thinker.Reasoner[B] {
+	return Epoch[B]{Reasoner: thinker.ReasonerOf(epoch.Reasoner), max: epoch.max}
+}
+
+func (epoch Epoch[B]) OnStart(ctx context.Context)            { onStart(ctx, epoch.Reasoner) }
+func (epoch Epoch[B]) OnEnd(ctx context.Context)              { onEnd(ctx, epoch.Reasoner) }
+func (epoch Epoch[B]) OnAbort(ctx context.Context, err error) { onAbort(ctx, err, epoch.Reasoner) }
+
 // Deduct new goal for the agent to pursue.
 func (epoch Epoch[B]) Deduct(state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
 	if state.Epoch >= epoch.max {
@@ -0,0 +1,224 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package reasoner
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/goccy/go-yaml"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// Transition of the workflow to the target state. The transition is taken
+// if the condition holds for the agent's state, nil condition always holds.
+type Transition[B any] struct {
+	When   func(thinker.State[B]) bool
+	Target string
+}
+
+type node[B any] struct {
+	prompt      *template.Template
+	transitions []Transition[B]
+}
+
+// The finite-state-machine reasoner runs the workflow (e.g. classify, then
+// extract, then verify) declared by the transition table. Each state has
+// the prompt and the transitions, which are evaluated in order against
+// the reply of the state. The prompt of the target state is asked next.
+// The state without transitions is terminal, the workflow returns results
+// after its reply. The initial state is served by the agent's input.
+// The reply rejected by the decoder is refined within the current state.
+type FSM[B any] struct {
+	initial string
+	current string
+	nodes   map[string]*node[B]
+}
+
+var _ thinker.ForkReasoner[any] = (*FSM[any])(nil)
+
+// Creates new finite-state-machine reasoner that starts at the initial state.
+func NewFSM[B any](initial string) *FSM[B] {
+	return &FSM[B]{
+		initial: initial,
+		current: initial,
+		nodes:   make(map[string]*node[B]),
+	}
+}
+
+// Declares the state of the workflow. The prompt is text/template rendered
+// with the agent's state (thinker.State) on entering the state, the empty
+// prompt returns results immediately. It panics if the template is invalid.
+//
+//	NewFSM[string]("classify").
+//		WithState("classify", "",
+//			reasoner.Transition[string]{When: isInvoice, Target: "extract"},
+//		).
+//		WithState("extract", "Extract the total amount from the {{.Reply}}.")
+func (fsm *FSM[B]) WithState(name string, prompt string, transitions ...Transition[B]) *FSM[B] {
+	fsm.nodes[name] = &node[B]{
+		prompt:      template.Must(template.New(name).Parse(prompt)),
+		transitions: transitions,
+	}
+	return fsm
+}
+
+// Current state of the workflow.
+func (fsm *FSM[B]) State() string { return fsm.current }
+
+// Purge resets the workflow to the initial state.
+func (fsm *FSM[B]) Purge() { fsm.current = fsm.initial }
+
+// Fork creates the workflow at the initial state, the transition table is shared.
+func (fsm *FSM[B]) Fork() thinker.Reasoner[B] {
+	return &FSM[B]{initial: fsm.initial, current: fsm.initial, nodes: fsm.nodes}
+}
+
+// Deduct new goal for the agent to pursue.
+func (fsm *FSM[B]) Deduct(state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
+	phase, prompt, err := fsm.deduct(state)
+	if phase == thinker.AGENT_RETURN || phase == thinker.AGENT_ABORT {
+		fsm.Purge()
+	}
+	return phase, prompt, err
+}
+
+func (fsm *FSM[B]) deduct(state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
+	current, has := fsm.nodes[fsm.current]
+	if !has {
+		return thinker.AGENT_ABORT, nil, thinker.ErrNoState.With(thinker.ErrAborted, fsm.current)
+	}
+
+	// the reply rejected by the decoder is refined within the current state
+	if state.Feedback != nil {
+		var prompt chatter.Prompt
+		prompt.With(state.Feedback)
+		return thinker.AGENT_REFINE, &prompt, nil
+	}
+
+	if len(current.transitions) == 0 {
+		return thinker.AGENT_RETURN, nil, nil
+	}
+
+	for _, transition := range current.transitions {
+		if transition.When != nil && !transition.When(state) {
+			continue
+		}
+
+		target, has := fsm.nodes[transition.Target]
+		if !has {
+			return thinker.AGENT_ABORT, nil, thinker.ErrNoState.With(thinker.ErrAborted, transition.Target)
+		}
+		fsm.current = transition.Target
+
+		var sb strings.Builder
+		if err := target.prompt.Execute(&sb, state); err != nil {
+			return thinker.AGENT_ABORT, nil, err
+		}
+
+		if len(strings.TrimSpace(sb.String())) == 0 {
+			return thinker.AGENT_RETURN, nil, nil
+		}
+
+		var prompt chatter.Prompt
+		prompt.WithTask("%s", sb.String())
+		return thinker.AGENT_ASK, &prompt, nil
+	}
+
+	return thinker.AGENT_ABORT, nil, thinker.ErrNoTransit.With(thinker.ErrAborted, fsm.current)
+}
+
+//------------------------------------------------------------------------------
+
+type yamlFSM struct {
+	Initial string               `yaml:"initial"`
+	States  map[string]yamlState `yaml:"states"`
+}
+
+type yamlState struct {
+	Prompt      string           `yaml:"prompt,omitempty"`
+	Transitions []yamlTransition `yaml:"transitions,omitempty"`
+}
+
+type yamlTransition struct {
+	When   string `yaml:"when,omitempty"`
+	Target string `yaml:"target"`
+}
+
+// Build the finite-state-machine reasoner from YAML. The condition of
+// transition is text/template rendered with the agent's state, it holds if
+// the template renders "true".
+//
+//	initial: classify
+//	states:
+//	  classify:
+//	    transitions:
+//	      - when: '{{eq .Reply "invoice"}}'
+//	        target: extract
+//	      - target: reject
+//	  extract:
+//	    prompt: Extract the total amount from the invoice.
+//	  reject: {}
+func ParseFSM[B any](data []byte) (*FSM[B], error) {
+	var spec yamlFSM
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+
+	if _, has := spec.States[spec.Initial]; !has {
+		return nil, fmt.Errorf("initial state %q is not defined", spec.Initial)
+	}
+
+	fsm := NewFSM[B](spec.Initial)
+	for name, state := range spec.States {
+		prompt, err := template.New(name).Parse(state.Prompt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt of state %q: %w", name, err)
+		}
+
+		transitions := make([]Transition[B], 0, len(state.Transitions))
+		for _, t := range state.Transitions {
+			if _, has := spec.States[t.Target]; !has {
+				return nil, fmt.Errorf("state %q transits to undefined state %q", name, t.Target)
+			}
+
+			when, err := condition[B](t.When)
+			if err != nil {
+				return nil, fmt.Errorf("invalid condition of state %q: %w", name, err)
+			}
+
+			transitions = append(transitions, Transition[B]{When: when, Target: t.Target})
+		}
+
+		fsm.nodes[name] = &node[B]{prompt: prompt, transitions: transitions}
+	}
+
+	return fsm, nil
+}
+
+func condition[B any](text string) (func(thinker.State[B]) bool, error) {
+	if len(strings.TrimSpace(text)) == 0 {
+		return nil, nil
+	}
+
+	when, err := template.New("when").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(state thinker.State[B]) bool {
+		var sb strings.Builder
+		if err := when.Execute(&sb, state); err != nil {
+			return false
+		}
+		return strings.TrimSpace(sb.String()) == "true"
+	}, nil
+}
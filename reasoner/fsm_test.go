@@ -0,0 +1,194 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package reasoner_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/reasoner"
+)
+
+func TestFSM(t *testing.T) {
+	is := func(text string) func(thinker.State[string]) bool {
+		return func(s thinker.State[string]) bool { return s.Reply == text }
+	}
+
+	workflow := func() *reasoner.FSM[string] {
+		return reasoner.NewFSM[string]("classify").
+			WithState("classify", "",
+				reasoner.Transition[string]{When: is("invoice"), Target: "extract"},
+				reasoner.Transition[string]{Target: "reject"},
+			).
+			WithState("extract", "Extract the total amount from the {{.Reply}}",
+				reasoner.Transition[string]{Target: "verify"},
+			).
+			WithState("verify", "Verify the amount {{.Reply}}").
+			WithState("reject", "")
+	}
+
+	t.Run("Workflow", func(t *testing.T) {
+		r := workflow()
+
+		phase, msg, err := r.Deduct(thinker.State[string]{Reply: "invoice"})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_ASK),
+			it.String(msg.String()).Contain("Extract the total amount from the invoice"),
+			it.Equal(r.State(), "extract"),
+		)
+
+		phase, msg, err = r.Deduct(thinker.State[string]{Reply: "42"})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_ASK),
+			it.String(msg.String()).Contain("Verify the amount 42"),
+			it.Equal(r.State(), "verify"),
+		)
+
+		phase, msg, err = r.Deduct(thinker.State[string]{Reply: "ok"})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(msg),
+			it.Equal(phase, thinker.AGENT_RETURN),
+			it.Equal(r.State(), "classify"),
+		)
+	})
+
+	t.Run("Terminal", func(t *testing.T) {
+		r := workflow()
+
+		phase, msg, err := r.Deduct(thinker.State[string]{Reply: "receipt"})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(msg),
+			it.Equal(phase, thinker.AGENT_RETURN),
+			it.Equal(r.State(), "classify"),
+		)
+	})
+
+	t.Run("Feedback", func(t *testing.T) {
+		r := workflow()
+		r.Deduct(thinker.State[string]{Reply: "invoice"})
+
+		feedback := chatter.Feedback{Note: "Improve the reply.", Text: []string{"amount is not a number"}}
+		phase, msg, err := r.Deduct(thinker.State[string]{Feedback: feedback})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_REFINE),
+			it.String(msg.String()).Contain("amount is not a number"),
+			it.Equal(r.State(), "extract"),
+		)
+	})
+
+	t.Run("NoTransition", func(t *testing.T) {
+		r := reasoner.NewFSM[string]("classify").
+			WithState("classify", "", reasoner.Transition[string]{When: is("invoice"), Target: "extract"}).
+			WithState("extract", "Extract")
+
+		phase, _, err := r.Deduct(thinker.State[string]{Reply: "receipt"})
+		it.Then(t).Should(
+			it.Equal(phase, thinker.AGENT_ABORT),
+			it.True(errors.Is(err, thinker.ErrAborted)),
+			it.String(err.Error()).Contain("no transition from state classify"),
+		)
+	})
+
+	t.Run("NoState", func(t *testing.T) {
+		r := reasoner.NewFSM[string]("classify").
+			WithState("classify", "", reasoner.Transition[string]{Target: "extract"})
+
+		phase, _, err := r.Deduct(thinker.State[string]{Reply: "invoice"})
+		it.Then(t).Should(
+			it.Equal(phase, thinker.AGENT_ABORT),
+			it.String(err.Error()).Contain("unknown state extract"),
+		)
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		r := workflow()
+		r.Deduct(thinker.State[string]{Reply: "invoice"})
+		r.Purge()
+
+		it.Then(t).Should(
+			it.Equal(r.State(), "classify"),
+		)
+	})
+}
+
+func TestParseFSM(t *testing.T) {
+	t.Run("Workflow", func(t *testing.T) {
+		r, err := reasoner.ParseFSM[string]([]byte(`
+initial: classify
+states:
+  classify:
+    transitions:
+      - when: '{{eq .Reply "invoice"}}'
+        target: extract
+      - target: reject
+  extract:
+    prompt: Extract the total amount from the {{.Reply}}
+    transitions:
+      - when: '{{ge .Confidence 0.8}}'
+        target: verify
+      - target: extract
+  verify:
+    prompt: Verify the amount {{.Reply}}
+  reject: {}
+`))
+		it.Then(t).Must(it.Nil(err))
+
+		phase, msg, err := r.Deduct(thinker.State[string]{Reply: "invoice"})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_ASK),
+			it.String(msg.String()).Contain("Extract the total amount from the invoice"),
+		)
+
+		_, _, err = r.Deduct(thinker.State[string]{Reply: "4", Confidence: 0.5})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(r.State(), "extract"),
+		)
+
+		phase, msg, err = r.Deduct(thinker.State[string]{Reply: "42", Confidence: 0.9})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_ASK),
+			it.String(msg.String()).Contain("Verify the amount 42"),
+		)
+
+		phase, _, _ = r.Deduct(thinker.State[string]{Reply: "ok"})
+		it.Then(t).Should(
+			it.Equal(phase, thinker.AGENT_RETURN),
+		)
+
+		phase, _, _ = r.Deduct(thinker.State[string]{Reply: "receipt"})
+		it.Then(t).Should(
+			it.Equal(phase, thinker.AGENT_RETURN),
+		)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for spec, expect := range map[string]string{
+			"initial: a\nstates:\n  b: {}\n":                                                       `initial state "a"`,
+			"initial: a\nstates:\n  a:\n    transitions:\n      - target: b\n":                     `undefined state "b"`,
+			"initial: a\nstates:\n  a:\n    prompt: '{{.Reply'\n":                                  `invalid prompt of state "a"`,
+			"initial: a\nstates:\n  a:\n    transitions:\n      - when: '{{'\n        target: a\n": `invalid condition`,
+		} {
+			_, err := reasoner.ParseFSM[string]([]byte(spec))
+			it.Then(t).Should(
+				it.String(err.Error()).Contain(expect),
+			)
+		}
+	})
+}
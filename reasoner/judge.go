@@ -0,0 +1,193 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package reasoner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/ledger"
+)
+
+// Verdicts of the judge
+const (
+	VERDICT_ACCEPT = "accept"
+	VERDICT_REFINE = "refine"
+	VERDICT_ABORT  = "abort"
+)
+
+// Verdict of the judge on the reply.
+type Verdict struct {
+	Verdict  string `json:"verdict"`
+	Critique string `json:"critique,omitempty"`
+}
+
+// The judge reasoner asks the grader LLM to evaluate the reply against
+// the goal (see thinker.State). The grader accepts the reply, asks to refine
+// it with the critique or aborts the agent. The grader is typically a model
+// different from the one used by the agent. The reply rejected by the decoder
+// is refined with its feedback, the grader is not called.
+//
+// The judge is forked for every run of the agent, the grader is called within
+// the context of the run (cancellation, tracing and the ledger). The judge is
+// immutable, concurrent runs share it safely. The judge used outside of
+// the agent calls the grader with the background context.
+type Judge[B any] struct {
+	llm      chatter.Chatter
+	model    string
+	criteria []string
+	format   func(B) string
+}
+
+var _ thinker.ForkReasoner[any] = (*Judge[any])(nil)
+
+// Creates new judge reasoner using the grader LLM.
+func NewJudge[B any](llm chatter.Chatter) *Judge[B] {
+	return &Judge[B]{llm: llm, format: formatReply[B]}
+}
+
+// Configures the model name of the grader, the usage of tokens by the grader
+// is accounted to this model instead of the agent's one (see ledger).
+func (j *Judge[B]) WithModel(model string) *Judge[B] {
+	j.model = model
+	return j
+}
+
+// Configures criteria used by the grader to evaluate the reply.
+func (j *Judge[B]) WithCriteria(criteria ...string) *Judge[B] {
+	j.criteria = criteria
+	return j
+}
+
+// Configures the text representation of the reply for the grader.
+// The reply is encoded as JSON by default.
+func (j *Judge[B]) WithFormat(format func(B) string) *Judge[B] {
+	j.format = format
+	return j
+}
+
+func (*Judge[B]) Purge() {}
+
+// Fork binds the judge to the run, the grader is called within the context
+// of the run.
+func (j *Judge[B]) Fork() thinker.Reasoner[B] {
+	return &judgeRun[B]{Judge: j, ctx: context.Background()}
+}
+
+// Deduct new goal for the agent to pursue.
+func (j *Judge[B]) Deduct(state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
+	return j.deduct(context.Background(), state)
+}
+
+func (j *Judge[B]) deduct(ctx context.Context, state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
+	if state.Feedback != nil {
+		var prompt chatter.Prompt
+		prompt.With(state.Feedback)
+		return thinker.AGENT_REFINE, &prompt, nil
+	}
+
+	verdict, err := j.Grade(ctx, state)
+	if err != nil {
+		return thinker.AGENT_ABORT, nil, err
+	}
+
+	switch verdict.Verdict {
+	case VERDICT_ACCEPT:
+		return thinker.AGENT_RETURN, nil, nil
+	case VERDICT_REFINE:
+		var prompt chatter.Prompt
+		prompt.WithTask("Refine the previous reply using the critique below.")
+		prompt.WithFeedback("Critique of the previous reply.", verdict.Critique)
+		return thinker.AGENT_REFINE, &prompt, nil
+	case VERDICT_ABORT:
+		return thinker.AGENT_ABORT, nil, thinker.ErrAborted.With(errors.New(verdict.Critique))
+	default:
+		return thinker.AGENT_ABORT, nil, thinker.ErrCodec.With(fmt.Errorf("unknown verdict %q", verdict.Verdict))
+	}
+}
+
+// Grade the reply against the goal.
+func (j *Judge[B]) Grade(ctx context.Context, state thinker.State[B]) (Verdict, error) {
+	var prompt chatter.Prompt
+	prompt.WithTask("Evaluate whether the reply achieves the goal.")
+	prompt.WithRules(
+		"Strictly follow the rules",
+		`Reply with JSON object only: {"verdict": "accept" | "refine" | "abort", "critique": "..."}.`,
+		`Use "accept" if the reply achieves the goal.`,
+		`Use "refine" if the reply can be improved, explain how in the critique.`,
+		`Use "abort" if the goal cannot be achieved, explain why in the critique.`,
+	)
+	if len(j.criteria) > 0 {
+		prompt.WithGuide("Evaluate the reply using the criteria", j.criteria...)
+	}
+	if state.Goal != nil {
+		prompt.WithBlob("Goal", state.Goal.String())
+	}
+	prompt.WithBlob("Reply", j.format(state.Reply))
+
+	if len(j.model) > 0 {
+		ctx = ledger.WithModel(ctx, j.model)
+	}
+
+	ctx, span := thinker.StartSpan(ctx, "reasoner.judge")
+	reply, err := j.llm.Prompt(ctx, []chatter.Message{&prompt})
+	span.End(err)
+	if err != nil {
+		return Verdict{}, thinker.ErrLLM.With(err)
+	}
+	ledger.Account(ctx, reply.Usage)
+
+	text := reply.String()
+	head, tail := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if head == -1 || tail < head {
+		return Verdict{}, thinker.ErrCodec.With(fmt.Errorf("invalid verdict %q", text))
+	}
+
+	var verdict Verdict
+	if err := json.Unmarshal([]byte(text[head:tail+1]), &verdict); err != nil {
+		return Verdict{}, thinker.ErrCodec.With(err)
+	}
+	verdict.Verdict = strings.ToLower(strings.TrimSpace(verdict.Verdict))
+
+	return verdict, nil
+}
+
+// the judge bound to the run of the agent
+type judgeRun[B any] struct {
+	*Judge[B]
+	ctx context.Context
+}
+
+func (r *judgeRun[B]) OnStart(ctx context.Context)    { r.ctx = ctx }
+func (r *judgeRun[B]) OnEnd(context.Context)          {}
+func (r *judgeRun[B]) OnAbort(context.Context, error) {}
+
+func (r *judgeRun[B]) Deduct(state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
+	return r.deduct(r.ctx, state)
+}
+
+func formatReply[B any](reply B) string {
+	switch v := any(reply).(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	bin, err := json.Marshal(reply)
+	if err != nil {
+		return fmt.Sprintf("%v", reply)
+	}
+	return string(bin)
+}
@@ -0,0 +1,220 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package reasoner_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/agent"
+	"github.com/kshard/thinker/codec"
+	"github.com/kshard/thinker/ledger"
+	"github.com/kshard/thinker/memory"
+	"github.com/kshard/thinker/reasoner"
+)
+
+// grader replies with the fixed verdict, it records the prompt
+type grader struct {
+	verdict string
+	err     error
+	prompt  string
+	ctx     context.Context
+}
+
+func (g *grader) Usage() chatter.Usage { return chatter.Usage{} }
+
+func (g *grader) Prompt(ctx context.Context, seq []chatter.Message, _ ...chatter.Opt) (*chatter.Reply, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	g.ctx = ctx
+	g.prompt = seq[0].String()
+	return &chatter.Reply{
+		Content: []chatter.Content{chatter.Text(g.verdict)},
+		Usage:   chatter.Usage{InputTokens: 10, ReplyTokens: 5},
+	}, nil
+}
+
+// echo replies with the prompt
+type echo struct{}
+
+func (echo) Usage() chatter.Usage { return chatter.Usage{} }
+
+func (echo) Prompt(_ context.Context, seq []chatter.Message, _ ...chatter.Opt) (*chatter.Reply, error) {
+	return &chatter.Reply{Content: []chatter.Content{chatter.Text(seq[len(seq)-1].String())}}, nil
+}
+
+func TestJudge(t *testing.T) {
+	state := thinker.State[[]string]{
+		Goal:  chatter.Task("List three colors of the rainbow."),
+		Reply: []string{"red", "green"},
+	}
+
+	t.Run("Accept", func(t *testing.T) {
+		llm := &grader{verdict: `{"verdict": "accept"}`}
+		phase, msg, err := reasoner.NewJudge[[]string](llm).
+			WithCriteria("exactly three colors").
+			Deduct(state)
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(msg),
+			it.Equal(phase, thinker.AGENT_RETURN),
+			it.String(llm.prompt).Contain("List three colors of the rainbow."),
+			it.String(llm.prompt).Contain(`["red","green"]`),
+			it.String(llm.prompt).Contain("exactly three colors"),
+		)
+	})
+
+	t.Run("Refine", func(t *testing.T) {
+		llm := &grader{verdict: "Sure!\n```json\n{\"verdict\": \"Refine\", \"critique\": \"only two colors\"}\n```"}
+		phase, msg, err := reasoner.NewJudge[[]string](llm).Deduct(state)
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_REFINE),
+			it.String(msg.String()).Contain("only two colors"),
+		)
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		llm := &grader{verdict: `{"verdict": "abort", "critique": "not a rainbow"}`}
+		phase, _, err := reasoner.NewJudge[[]string](llm).Deduct(state)
+
+		it.Then(t).Should(
+			it.Equal(phase, thinker.AGENT_ABORT),
+			it.True(errors.Is(err, thinker.ErrAborted)),
+			it.String(err.Error()).Contain("not a rainbow"),
+		)
+	})
+
+	t.Run("InvalidVerdict", func(t *testing.T) {
+		for _, verdict := range []string{"looks good", `{"verdict": "maybe"}`} {
+			phase, _, err := reasoner.NewJudge[[]string](&grader{verdict: verdict}).Deduct(state)
+
+			it.Then(t).Should(
+				it.Equal(phase, thinker.AGENT_ABORT),
+				it.True(errors.Is(err, thinker.ErrCodec)),
+			)
+		}
+	})
+
+	t.Run("GraderFailed", func(t *testing.T) {
+		phase, _, err := reasoner.NewJudge[[]string](&grader{err: errors.New("throttled")}).Deduct(state)
+
+		it.Then(t).Should(
+			it.Equal(phase, thinker.AGENT_ABORT),
+			it.True(errors.Is(err, thinker.ErrLLM)),
+		)
+	})
+
+	t.Run("Feedback", func(t *testing.T) {
+		llm := &grader{verdict: `{"verdict": "accept"}`}
+		rejected := state
+		rejected.Feedback = chatter.Feedback{Note: "Improve the reply.", Text: []string{"reply is not JSON"}}
+		phase, msg, err := reasoner.NewJudge[[]string](llm).Deduct(rejected)
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_REFINE),
+			it.String(msg.String()).Contain("reply is not JSON"),
+			it.Equal(llm.prompt, ""),
+		)
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		type key struct{}
+		ctx := context.WithValue(context.Background(), key{}, "run")
+
+		llm := &grader{verdict: `{"verdict": "accept"}`}
+		judge := thinker.ReasonerOf[[]string](reasoner.NewJudge[[]string](llm))
+		thinker.OnStart(ctx, judge)
+		_, _, err := judge.Deduct(state)
+
+		it.Then(t).Must(it.Nil(err))
+		it.Then(t).Should(
+			it.Equal(llm.ctx.Value(key{}), any("run")),
+		)
+	})
+
+	t.Run("FeedbackOnce", func(t *testing.T) {
+		llm := &grader{verdict: `{"verdict": "accept"}`}
+		decoded := 0
+		automata := agent.NewAutomata(&echo{},
+			memory.NewVoid(""),
+			codec.String,
+			codec.FromDecoder(func(reply *chatter.Reply) (float64, string, error) {
+				decoded++
+				if decoded == 1 {
+					return 0.0, "", thinker.Feedback("rejected")
+				}
+				return 1.0, reply.String(), nil
+			}),
+			reasoner.NewEpoch(5, reasoner.NewJudge[string](llm)),
+		)
+
+		_, err := automata.Prompt(context.Background(), "goal")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(decoded, 2),
+			it.String(llm.prompt).Contain("goal"),
+		)
+	})
+
+	t.Run("Model", func(t *testing.T) {
+		l := ledger.New().WithPrice("grader", ledger.Price{Input: 1e6, Output: 1e6})
+		ctx := ledger.WithModel(l.Context(context.Background()), "agent")
+
+		judge := thinker.ReasonerOf[[]string](reasoner.NewJudge[[]string](&grader{verdict: `{"verdict": "accept"}`}).WithModel("grader"))
+		thinker.OnStart(ctx, judge)
+		_, _, err := judge.Deduct(state)
+		it.Then(t).Must(it.Nil(err))
+
+		report := l.Report()
+		it.Then(t).Must(it.Equal(len(report.Lines), 1))
+		it.Then(t).Should(
+			it.Equal(report.Lines[0].Model, "grader"),
+			it.Equal(report.Total.Cost, 15.0),
+		)
+	})
+
+	t.Run("Shared", func(t *testing.T) {
+		type key struct{}
+		llm := &grader{verdict: `{"verdict": "accept"}`}
+		judge := reasoner.NewJudge[[]string](llm)
+
+		a := thinker.ReasonerOf[[]string](judge)
+		b := thinker.ReasonerOf[[]string](judge)
+		thinker.OnStart(context.WithValue(context.Background(), key{}, "a"), a)
+		thinker.OnStart(context.WithValue(context.Background(), key{}, "b"), b)
+		thinker.OnEnd(context.Background(), b)
+
+		_, _, err := a.Deduct(state)
+		it.Then(t).Must(it.Nil(err))
+		it.Then(t).Should(
+			it.Equal(llm.ctx.Value(key{}), any("a")),
+		)
+	})
+
+	t.Run("Format", func(t *testing.T) {
+		llm := &grader{verdict: `{"verdict": "accept"}`}
+		reasoner.NewJudge[[]string](llm).
+			WithFormat(func(seq []string) string { return "colors: red & green" }).
+			Deduct(state)
+
+		it.Then(t).Should(
+			it.String(llm.prompt).Contain("colors: red & green"),
+		)
+	})
+}
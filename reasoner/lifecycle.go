@@ -0,0 +1,45 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package reasoner
+
+import (
+	"context"
+
+	"github.com/kshard/thinker"
+)
+
+// Composite reasoners (decorators and combinators) fork their components
+// and propagate lifecycle of the run to them, so that stateful components
+// are isolated wherever they are placed.
+
+func fork[B any](seq []thinker.Reasoner[B]) []thinker.Reasoner[B] {
+	forked := make([]thinker.Reasoner[B], len(seq))
+	for i, r := range seq {
+		forked[i] = thinker.ReasonerOf(r)
+	}
+	return forked
+}
+
+func onStart[B any](ctx context.Context, seq ...thinker.Reasoner[B]) {
+	for _, r := range seq {
+		thinker.OnStart(ctx, r)
+	}
+}
+
+func onEnd[B any](ctx context.Context, seq ...thinker.Reasoner[B]) {
+	for _, r := range seq {
+		thinker.OnEnd(ctx, r)
+	}
+}
+
+func onAbort[B any](ctx context.Context, err error, seq ...thinker.Reasoner[B]) {
+	for _, r := range seq {
+		thinker.OnAbort(ctx, err, r)
+	}
+}
@@ -0,0 +1,66 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package reasoner_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/reasoner"
+)
+
+// hooks records lifecycle of the run
+type hooks struct {
+	thinker.Reasoner[string]
+	seq *[]string
+}
+
+func (h hooks) OnStart(context.Context)        { *h.seq = append(*h.seq, "start") }
+func (h hooks) OnEnd(context.Context)          { *h.seq = append(*h.seq, "end") }
+func (h hooks) OnAbort(context.Context, error) { *h.seq = append(*h.seq, "abort") }
+
+func TestLifecycle(t *testing.T) {
+	t.Run("Fork", func(t *testing.T) {
+		fsm := reasoner.NewFSM[string]("draft").
+			WithState("draft", "", reasoner.Transition[string]{Target: "review"}).
+			WithState("review", "Review the draft")
+
+		for _, r := range []thinker.Reasoner[string]{
+			reasoner.NewEpoch(3, fsm),
+			reasoner.NewBudget(reasoner.NewSpend(reasoner.Limits{}), fsm),
+			reasoner.Seq[string](fsm),
+			reasoner.OnPhase(thinker.AGENT_ASK, fsm),
+			reasoner.Fallback[string](fsm),
+		} {
+			forked := thinker.ReasonerOf(r)
+			phase, _, err := forked.Deduct(thinker.State[string]{})
+			it.Then(t).Should(
+				it.Nil(err),
+				it.Equal(phase, thinker.AGENT_ASK),
+				it.Equal(fsm.State(), "draft"),
+			)
+		}
+	})
+
+	t.Run("Hooks", func(t *testing.T) {
+		seq := []string{}
+		r := reasoner.NewEpoch(3, reasoner.Seq[string](hooks{Reasoner: reasoner.NewVoid[string](), seq: &seq}))
+
+		thinker.OnStart(context.Background(), r)
+		thinker.OnEnd(context.Background(), r)
+		thinker.OnAbort(context.Background(), errors.New("abort"), r)
+
+		it.Then(t).Should(
+			it.Seq(seq).Equal("start", "end", "abort"),
+		)
+	})
+}
@@ -0,0 +1,89 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package reasoner
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+)
+
+// Default task of the refine prompt.
+const DefaultRefine = "Refine the previous request using the feedback below."
+
+// Default number of attempts to reach the confidence level.
+const DefaultAttempts = 3
+
+// The threshold reasoner returns results if the decoder's confidence is at
+// or above the level. Below the level, it refines the request with feedback
+// given by the decoder or retries the request if there is no feedback.
+// The agent is aborted with thinker.ErrMaxSteps if the level is not reached
+// within the number of attempts (epochs of the step).
+type Threshold[B any] struct {
+	level    float64
+	attempts int
+	refine   *template.Template
+}
+
+var _ thinker.Reasoner[any] = (*Threshold[any])(nil)
+
+// Creates new threshold reasoner that accepts results at the confidence level.
+func NewThreshold[B any](level float64) *Threshold[B] {
+	return &Threshold[B]{
+		level:    level,
+		attempts: DefaultAttempts,
+		refine:   template.Must(template.New("refine").Parse(DefaultRefine)),
+	}
+}
+
+// Configures the number of attempts to reach the confidence level, including
+// the first one. The number of attempts is unbounded if zero, use Epoch then.
+func (t *Threshold[B]) WithAttempts(n int) *Threshold[B] {
+	t.attempts = n
+	return t
+}
+
+// Configures the task of the refine prompt. The text/template is rendered
+// with the agent's state (thinker.State), the feedback is appended to the
+// prompt. It panics if the template is invalid.
+//
+//	NewThreshold[B](0.8).WithRefine("Confidence {{.Confidence}} is too low, fix the reply.")
+func (t *Threshold[B]) WithRefine(text string) *Threshold[B] {
+	t.refine = template.Must(template.New("refine").Parse(text))
+	return t
+}
+
+func (*Threshold[B]) Purge() {}
+
+// Deduct new goal for the agent to pursue.
+func (t *Threshold[B]) Deduct(state thinker.State[B]) (thinker.Phase, chatter.Message, error) {
+	if state.Confidence >= t.level {
+		return thinker.AGENT_RETURN, nil, nil
+	}
+
+	if t.attempts > 0 && state.Epoch >= t.attempts {
+		return thinker.AGENT_ABORT, nil, thinker.ErrMaxSteps.With(thinker.ErrAborted)
+	}
+
+	if state.Feedback == nil {
+		return thinker.AGENT_RETRY, nil, nil
+	}
+
+	var sb strings.Builder
+	if err := t.refine.Execute(&sb, state); err != nil {
+		return thinker.AGENT_ABORT, nil, err
+	}
+
+	var prompt chatter.Prompt
+	prompt.WithTask("%s", sb.String())
+	prompt.With(state.Feedback)
+	return thinker.AGENT_REFINE, &prompt, nil
+}
@@ -0,0 +1,114 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package reasoner_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/chatter"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/reasoner"
+)
+
+func TestThreshold(t *testing.T) {
+	feedback := chatter.Feedback{Note: "Fix the reply.", Text: []string{"invalid JSON"}}
+
+	t.Run("Return", func(t *testing.T) {
+		r := reasoner.NewThreshold[string](0.8)
+		phase, msg, err := r.Deduct(thinker.State[string]{Confidence: 0.8, Feedback: feedback})
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(msg),
+			it.Equal(phase, thinker.AGENT_RETURN),
+		)
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		r := reasoner.NewThreshold[string](0.8)
+		phase, msg, err := r.Deduct(thinker.State[string]{Confidence: 0.5})
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(msg),
+			it.Equal(phase, thinker.AGENT_RETRY),
+		)
+	})
+
+	t.Run("Refine", func(t *testing.T) {
+		r := reasoner.NewThreshold[string](0.8)
+		phase, msg, err := r.Deduct(thinker.State[string]{Confidence: 0.5, Feedback: feedback})
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_REFINE),
+			it.String(msg.String()).Contain(reasoner.DefaultRefine),
+			it.String(msg.String()).Contain("invalid JSON"),
+		)
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		r := reasoner.NewThreshold[string](0.8).
+			WithRefine("Attempt {{.Epoch}} has confidence {{.Confidence}}, reply {{.Reply}} is wrong")
+		phase, msg, err := r.Deduct(
+			thinker.State[string]{Epoch: 2, Confidence: 0.5, Reply: "abc", Feedback: feedback},
+		)
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_REFINE),
+			it.String(msg.String()).Contain("Attempt 2 has confidence 0.5, reply abc is wrong"),
+			it.String(msg.String()).Contain("invalid JSON"),
+		)
+	})
+
+	t.Run("TemplateFailed", func(t *testing.T) {
+		r := reasoner.NewThreshold[string](0.8).WithRefine("{{.Unknown}}")
+		phase, _, err := r.Deduct(thinker.State[string]{Confidence: 0.5, Feedback: feedback})
+
+		it.Then(t).Should(
+			it.Equal(phase, thinker.AGENT_ABORT),
+		).ShouldNot(
+			it.Nil(err),
+		)
+	})
+
+	t.Run("Attempts", func(t *testing.T) {
+		r := reasoner.NewThreshold[string](0.8)
+		phase, _, err := r.Deduct(thinker.State[string]{Epoch: reasoner.DefaultAttempts - 1, Confidence: 0.5})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_RETRY),
+		)
+
+		phase, _, err = r.Deduct(thinker.State[string]{Epoch: reasoner.DefaultAttempts, Confidence: 0.5, Feedback: feedback})
+		it.Then(t).Should(
+			it.Equal(phase, thinker.AGENT_ABORT),
+			it.True(errors.Is(err, thinker.ErrMaxSteps)),
+			it.True(errors.Is(err, thinker.ErrAborted)),
+		)
+
+		phase, _, err = r.Deduct(thinker.State[string]{Epoch: reasoner.DefaultAttempts, Confidence: 0.9})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_RETURN),
+		)
+	})
+
+	t.Run("Unbounded", func(t *testing.T) {
+		r := reasoner.NewThreshold[string](0.8).WithAttempts(0)
+		phase, _, err := r.Deduct(thinker.State[string]{Epoch: 100, Confidence: 0.5})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(phase, thinker.AGENT_RETRY),
+		)
+	})
+}
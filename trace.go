@@ -0,0 +1,51 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package thinker
+
+import "context"
+
+// Tracer records spans of the execution: runs of agents, epochs, LLM calls
+// and tool invocations. The tracer is propagated through the context, the span
+// started within the context of another span is its child.
+//
+// See package `tracer` that implements the tracer and exporters.
+type Tracer interface {
+	// Starts the span, the returned context carries the span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is the timed operation of the execution.
+type Span interface {
+	// Annotates the span with the attribute (e.g. model, tokens, phase, tool).
+	With(key string, value any) Span
+
+	// Ends the span, the error marks the failed operation.
+	End(error)
+}
+
+type tracerKey struct{}
+
+// Binds the tracer to the context.
+func WithTracer(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+// Starts the span using the tracer bound to the context. The span is no-op
+// if the context has no tracer.
+func StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	if tracer, ok := ctx.Value(tracerKey{}).(Tracer); ok && tracer != nil {
+		return tracer.Start(ctx, name)
+	}
+	return ctx, void{}
+}
+
+type void struct{}
+
+func (v void) With(string, any) Span { return v }
+func (void) End(error)               {}
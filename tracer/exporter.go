@@ -0,0 +1,120 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package tracer
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Memory exporter retains spans in the order of their end, it is designed
+// for tests and debugging.
+type Memory struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+var _ Exporter = (*Memory)(nil)
+
+// Creates new in-memory exporter.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Export(span Span) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.spans = append(m.spans, span)
+}
+
+// Returns ended spans.
+func (m *Memory) Spans() []Span {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	spans := make([]Span, len(m.spans))
+	copy(spans, m.spans)
+	return spans
+}
+
+// Returns ended spans with the name.
+func (m *Memory) Lookup(name string) []Span {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	spans := make([]Span, 0)
+	for _, s := range m.spans {
+		if s.Name == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// Forgets ended spans.
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.spans = nil
+}
+
+// JSONL exporter writes every span as JSON line.
+type JSONL struct {
+	mu sync.Mutex
+	w  io.Writer
+	fd *os.File
+}
+
+var _ Exporter = (*JSONL)(nil)
+
+// Creates new JSON lines exporter to the writer.
+func NewJSONL(w io.Writer) *JSONL {
+	return &JSONL{w: w}
+}
+
+// Creates new JSON lines exporter, appending spans to the file.
+func OpenJSONL(path string) (*JSONL, error) {
+	fd, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSONL{w: fd, fd: fd}, nil
+}
+
+func (j *JSONL) Export(span Span) {
+	bin, err := json.Marshal(span)
+	if err != nil {
+		slog.Warn("failed to encode span", "name", span.Name, "err", err)
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.w.Write(append(bin, '\n')); err != nil {
+		slog.Warn("failed to export span", "name", span.Name, "err", err)
+	}
+}
+
+// Close the file opened by the exporter.
+func (j *JSONL) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.fd == nil {
+		return nil
+	}
+	return j.fd.Close()
+}
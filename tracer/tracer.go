@@ -0,0 +1,120 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package tracer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fogfish/guid/v2"
+	"github.com/kshard/thinker"
+)
+
+// Span is the record of the ended operation.
+type Span struct {
+	// Identity of the trace, it is the identity of the root span
+	Trace string `json:"trace"`
+
+	// Identity of the span and its parent, the root span has no parent
+	ID     string `json:"id"`
+	Parent string `json:"parent,omitempty"`
+
+	Name    string         `json:"name"`
+	Started time.Time      `json:"started"`
+	Latency time.Duration  `json:"latency"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// Exporter receives spans as they end.
+type Exporter interface {
+	Export(Span)
+}
+
+// Tracer records spans and passes them to the exporter.
+type Tracer struct {
+	exporter Exporter
+	clock    func() time.Time
+}
+
+var _ thinker.Tracer = (*Tracer)(nil)
+
+// Creates new tracer, exporting spans to the exporter.
+func New(exporter Exporter) *Tracer {
+	return &Tracer{
+		exporter: exporter,
+		clock:    time.Now,
+	}
+}
+
+// Binds the tracer to the context.
+//
+//	ctx := tracer.New(tracer.NewMemory()).Context(context.Background())
+func (t *Tracer) Context(ctx context.Context) context.Context {
+	return thinker.WithTracer(ctx, t)
+}
+
+type spanKey struct{}
+
+// Starts the span, it is the child of the span in the context.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, thinker.Span) {
+	s := &span{
+		tracer: t,
+		Span: Span{
+			ID:      guid.G(guid.Clock).String(),
+			Name:    name,
+			Started: t.clock(),
+		},
+	}
+
+	if parent, ok := ctx.Value(spanKey{}).(*span); ok {
+		s.Trace = parent.Trace
+		s.Parent = parent.ID
+	} else {
+		s.Trace = s.ID
+	}
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+type span struct {
+	Span
+	mu     sync.Mutex
+	tracer *Tracer
+	ended  bool
+}
+
+func (s *span) With(key string, value any) thinker.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Attrs == nil {
+		s.Attrs = make(map[string]any)
+	}
+	s.Attrs[key] = value
+	return s
+}
+
+func (s *span) End(err error) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.Latency = s.tracer.clock().Sub(s.Started)
+	if err != nil {
+		s.Error = err.Error()
+	}
+	record := s.Span
+	s.mu.Unlock()
+
+	s.tracer.exporter.Export(record)
+}
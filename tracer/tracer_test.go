@@ -0,0 +1,114 @@
+//
+// Copyright (C) 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/kshard/thinker
+//
+
+package tracer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/thinker"
+	"github.com/kshard/thinker/tracer"
+)
+
+func TestTracer(t *testing.T) {
+	t.Run("Void", func(t *testing.T) {
+		ctx, span := thinker.StartSpan(context.Background(), "void")
+		span.With("key", "value").End(nil)
+
+		it.Then(t).Should(
+			it.Equal(ctx, context.Background()),
+		)
+	})
+
+	t.Run("Causality", func(t *testing.T) {
+		mem := tracer.NewMemory()
+		ctx := tracer.New(mem).Context(context.Background())
+
+		rctx, root := thinker.StartSpan(ctx, "root")
+		_, a := thinker.StartSpan(rctx, "a")
+		a.With("model", "m").End(nil)
+		_, b := thinker.StartSpan(rctx, "b")
+		b.End(errors.New("failed"))
+		root.End(nil)
+
+		spans := mem.Spans()
+		it.Then(t).Must(it.Equal(len(spans), 3))
+		it.Then(t).Should(
+			it.Equal(spans[0].Name, "a"),
+			it.Equal(spans[0].Parent, spans[2].ID),
+			it.Equal(spans[0].Trace, spans[2].ID),
+			it.Equal(spans[0].Attrs["model"], any("m")),
+			it.Equal(spans[1].Error, "failed"),
+			it.Equal(spans[1].Parent, spans[2].ID),
+			it.Equal(spans[2].Parent, ""),
+			it.Equal(spans[2].Trace, spans[2].ID),
+			it.Equal(len(mem.Lookup("b")), 1),
+		)
+	})
+
+	t.Run("EndOnce", func(t *testing.T) {
+		mem := tracer.NewMemory()
+		ctx := tracer.New(mem).Context(context.Background())
+
+		_, span := thinker.StartSpan(ctx, "span")
+		span.End(nil)
+		span.End(errors.New("late"))
+
+		it.Then(t).Should(
+			it.Equal(len(mem.Spans()), 1),
+			it.Equal(mem.Spans()[0].Error, ""),
+		)
+	})
+
+	t.Run("JSONL", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		ctx := tracer.New(tracer.NewJSONL(buf)).Context(context.Background())
+
+		for _, name := range []string{"a", "b"} {
+			_, span := thinker.StartSpan(ctx, name)
+			span.With("tokens", 10).End(nil)
+		}
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		it.Then(t).Must(it.Equal(len(lines), 2))
+
+		var span tracer.Span
+		err := json.Unmarshal([]byte(lines[1]), &span)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(span.Name, "b"),
+			it.Equal(span.Attrs["tokens"], any(10.0)),
+		)
+	})
+
+	t.Run("OpenJSONL", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "trace.jsonl")
+		for range 2 {
+			exporter, err := tracer.OpenJSONL(path)
+			it.Then(t).Must(it.Nil(err))
+
+			_, span := thinker.StartSpan(tracer.New(exporter).Context(context.Background()), "run")
+			span.End(nil)
+			it.Then(t).Must(it.Nil(exporter.Close()))
+		}
+
+		bin, err := os.ReadFile(path)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(strings.Count(string(bin), "\n"), 2),
+		)
+	})
+}